@@ -1,24 +1,56 @@
 package main
 
 import (
-	"Calc_2GO/Internal/agent"
-	"log"
+	"Calc_2GO/internal/agent"
+	"Calc_2GO/pkg/logging"
+	"Calc_2GO/pkg/tracing"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	// URL оркестратора
-	orchestratorURL := "http://localhost:8080"
+	// URL внутреннего API оркестратора
+	orchestratorURL := os.Getenv("ORCHESTRATOR_URL")
+	if orchestratorURL == "" {
+		orchestratorURL = "http://localhost:8081"
+	}
 
 	// Количество горутин (вычислительных мощностей)
 	computingPower := 2
 
+	logger := logging.New(os.Stderr)
+
+	// Трассировки отправляются по OTLP, если задан OTEL_EXPORTER_OTLP_ENDPOINT
+	shutdown, err := tracing.Setup(context.Background(), "calc-agent")
+	if err != nil {
+		logger.Error("ошибка настройки трассировки", logging.Err(err))
+	}
+
 	// Создаем агента
-	agent := agent.NewAgent(orchestratorURL, computingPower)
+	agent := agent.NewAgent(orchestratorURL, computingPower, agent.WithLogger(logger))
 
 	// Запуск агента
-	log.Println("🚀 Запуск агента...")
-	agent.Start()
+	logger.Info("запуск агента", "orchestrator_url", orchestratorURL)
+	if err := agent.Start(); err != nil {
+		logger.Error("ошибка запуска агента", logging.Err(err))
+		os.Exit(1)
+	}
+
+	// Работаем до SIGINT или SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+	logger.Info("остановка агента")
 
-	// Бесконечное ожидание (чтобы программа не завершилась)
-	select {}
+	// Отправляем накопленные спаны до выхода
+	if shutdown != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			logger.Error("ошибка остановки трассировки", logging.Err(err))
+		}
+	}
 }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"Calc_2GO/pkg/client"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func (c *cli) eval(args []string) error {
+	fs := c.flags("eval")
+	wait := fs.Bool("wait", false, "дождаться результата")
+	timeout := fs.Duration("timeout", 0, "сколько ждать результата, 0 — без ограничения")
+	callback := fs.String("callback", "", "адрес для callback-запроса с результатом")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cl := c.client()
+	id, err := cl.Submit(ctx, positional[0], client.SubmitOptions{CallbackURL: *callback})
+	if err != nil {
+		return err
+	}
+	if !*wait {
+		if c.output == "json" {
+			return c.printJSON(map[string]int{"id": id})
+		}
+		fmt.Fprintln(c.stdout, id)
+		return nil
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	expr, err := cl.Wait(ctx, id)
+	if err != nil {
+		return fmt.Errorf("выражение %d: %w", id, err)
+	}
+	if err := c.printExpressions(expr); err != nil {
+		return err
+	}
+	if expr.Status != client.StatusDone {
+		return errNotDone
+	}
+	return nil
+}
+
+func (c *cli) list(args []string) error {
+	fs := c.flags("list")
+	var filter client.ListFilter
+	fs.StringVar(&filter.Status, "status", "", "статус: pending, in_progress, done, error или cancelled")
+	fs.StringVar(&filter.Sort, "sort", "", "сортировка: id или created_at, с префиксом - — по убыванию")
+	fs.IntVar(&filter.Limit, "limit", 0, "размер страницы")
+	fs.StringVar(&filter.Cursor, "cursor", "", "курсор следующей страницы")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	page, err := c.client().List(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(page)
+	}
+	if err := c.printExpressions(page.Expressions...); err != nil {
+		return err
+	}
+	if page.NextCursor != "" {
+		fmt.Fprintf(c.stderr, "Следующая страница: --cursor %s\n", page.NextCursor)
+	}
+	return nil
+}
+
+func (c *cli) get(args []string) error {
+	fs := c.flags("get")
+	withTasks := fs.Bool("tasks", false, "показать задачи выражения")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cl := c.client()
+	expr, err := cl.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !*withTasks {
+		return c.printExpressions(expr)
+	}
+
+	tasks, err := cl.Tasks(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(struct {
+			*client.Expression
+			Tasks []client.Task `json:"tasks"`
+		}{expr, tasks})
+	}
+	if err := c.printExpressions(expr); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout)
+	return c.printTasks(tasks)
+}
+
+func (c *cli) cancel(args []string) error {
+	fs := c.flags("cancel")
+	positional, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional[0])
+	if err != nil {
+		return err
+	}
+
+	expr, err := c.client().Cancel(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return c.printExpressions(expr)
+}
+
+func (c *cli) agents(args []string) error {
+	fs := c.flags("agents")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	agents, err := c.client().Agents(context.Background())
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(agents)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tHOSTNAME\tPOWER\tOPERATIONS\tVERSION\tLAST HEARTBEAT")
+	for _, a := range agents {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", a.ID, a.Status, a.Hostname, a.ComputingPower,
+			strings.Join(a.Operations, " "), a.Version, formatTime(&a.LastHeartbeat))
+	}
+	return w.Flush()
+}
+
+// printExpressions выводит выражения таблицей или, если выражение одно, JSON-объектом.
+func (c *cli) printExpressions(exprs ...*client.Expression) error {
+	if c.output == "json" {
+		if len(exprs) == 1 {
+			return c.printJSON(exprs[0])
+		}
+		return c.printJSON(exprs)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tRESULT\tTASKS\tCREATED\tEXPRESSION\tERROR")
+	for _, e := range exprs {
+		result := "-"
+		if e.Status == client.StatusDone {
+			result = formatFloat(e.Result)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d/%d\t%s\t%s\t%s\n", e.ID, e.Status, result, e.TasksDone, e.TasksTotal,
+			formatTime(&e.CreatedAt), e.Expression, e.Error)
+	}
+	return w.Flush()
+}
+
+func (c *cli) printTasks(tasks []client.Task) error {
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tOPERATION\tSTATUS\tRESULT\tAGENT\tATTEMPTS\tERROR")
+	for _, t := range tasks {
+		result := "-"
+		if t.Result != nil {
+			result = formatFloat(*t.Result)
+		}
+		agent := t.Agent
+		if t.Cached {
+			agent = "(кэш)"
+		}
+		fmt.Fprintf(w, "%d\t%s %s %s\t%s\t%s\t%s\t%d\t%s\n", t.ID, formatFloat(t.Arg1), t.Operation, formatFloat(t.Arg2),
+			t.Status, result, agent, t.Attempts, t.Error)
+	}
+	return w.Flush()
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: неверный ID %q", errUsage, s)
+	}
+	return id, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,183 @@
+// calcctl — клиент командной строки для оркестратора:
+//
+//	calcctl eval "2+2*2" --wait
+//	calcctl list --status pending
+//	calcctl get 1 --tasks
+//	calcctl cancel 1
+//	calcctl agents
+//
+// Адрес публичного API задаётся флагом --url или переменной CALC_URL,
+// API-ключ — флагом --api-key или переменной CALC_API_KEY.
+package main
+
+import (
+	"Calc_2GO/pkg/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const usage = `Использование: calcctl <команда> [флаги]
+
+Команды:
+  eval ВЫРАЖЕНИЕ [--wait] [--timeout 30s] [--callback URL]
+                      отправить выражение; с --wait дождаться результата
+  list [--status S] [--sort -id] [--limit N] [--cursor C]
+                      список выражений
+  get ID [--tasks]    выражение и, с --tasks, его задачи
+  cancel ID           отменить выражение
+  agents              зарегистрированные агенты
+
+Общие флаги:
+  --url URL           адрес публичного API (CALC_URL, по умолчанию http://localhost:8080)
+  --api-key KEY       API-ключ (CALC_API_KEY)
+  -o, --output FMT    формат вывода: table (по умолчанию) или json
+
+Флаги можно указывать до и после аргументов. Аргументы после -- флагами не считаются.
+`
+
+// errUsage — команда вызвана с неверными аргументами.
+var errUsage = errors.New("неверные аргументы")
+
+// errNotDone — выражение, которого дождалась команда, завершилось не успешно.
+var errNotDone = errors.New("выражение не вычислено")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run выполняет команду и возвращает код завершения: 0 — успех,
+// 1 — ошибка, 2 — неверные аргументы.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	commands := map[string]func(*cli, []string) error{
+		"eval":   (*cli).eval,
+		"list":   (*cli).list,
+		"get":    (*cli).get,
+		"cancel": (*cli).cancel,
+		"agents": (*cli).agents,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "❌ Неизвестная команда: %s\n\n%s", args[0], usage)
+		return 2
+	}
+
+	c := &cli{stdout: stdout, stderr: stderr}
+	err := command(c, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "❌ %v\n\n%s", err, usage)
+		return 2
+	case errors.Is(err, errNotDone):
+		return 1
+	default:
+		fmt.Fprintf(stderr, "❌ %v\n", err)
+		return 1
+	}
+}
+
+// cli — общие флаги и вывод команд.
+type cli struct {
+	stdout, stderr io.Writer
+
+	url    string
+	apiKey string
+	output string
+}
+
+// flags создаёт набор флагов команды с общими флагами.
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() { fmt.Fprint(c.stderr, usage) }
+
+	fs.StringVar(&c.url, "url", envOr("CALC_URL", "http://localhost:8080"), "адрес публичного API")
+	fs.StringVar(&c.apiKey, "api-key", os.Getenv("CALC_API_KEY"), "API-ключ")
+	fs.StringVar(&c.output, "output", "table", "формат вывода: table или json")
+	fs.StringVar(&c.output, "o", "table", "формат вывода: table или json")
+	return fs
+}
+
+// parse разбирает флаги, которые могут стоять и до, и после позиционных
+// аргументов, и проверяет, что позиционных аргументов ровно want. Флагом
+// считается аргумент, у которого после дефисов идёт буква, поэтому выражение
+// вроде -2+3 остаётся позиционным аргументом. После -- все аргументы позиционные.
+func (c *cli) parse(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !isFlag(arg) {
+			positional = append(positional, arg)
+			continue
+		}
+
+		flags = append(flags, arg)
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+
+	if err := fs.Parse(flags); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if len(positional) != want {
+		return nil, fmt.Errorf("%w: команда %s ожидает аргументов: %d, а получено: %d", errUsage, fs.Name(), want, len(positional))
+	}
+	if c.output != "table" && c.output != "json" {
+		return nil, fmt.Errorf("%w: неизвестный формат вывода %q", errUsage, c.output)
+	}
+	return positional, nil
+}
+
+// isFlag сообщает, похож ли аргумент на флаг: -name, --name или --name=value.
+func isFlag(arg string) bool {
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if name == arg || name == "" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsLetter(r)
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (c *cli) client() *client.Client {
+	return client.New(c.url, client.WithAPIKey(c.apiKey))
+}
+
+func envOr(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
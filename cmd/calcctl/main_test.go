@@ -0,0 +1,121 @@
+package main
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// solve выполняет задачи из очереди оркестратора, пока они не закончатся.
+func solve(o *orchestrator.Orchestrator) {
+	for {
+		tasks := o.NextTasks("agent-1", models.MaxBatchSize)
+		if len(tasks) == 0 {
+			return
+		}
+		results := make([]models.TaskResult, len(tasks))
+		for i, task := range tasks {
+			var result float64
+			switch task.Operation {
+			case "+":
+				result = task.Arg1 + task.Arg2
+			case "-":
+				result = task.Arg1 - task.Arg2
+			case "*":
+				result = task.Arg1 * task.Arg2
+			case "/":
+				result = task.Arg1 / task.Arg2
+			}
+			results[i] = models.TaskResult{ID: task.ID, Result: result}
+		}
+		o.RecordResults("agent-1", results)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	defer srv.Close()
+
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-1", Hostname: "host-1", ComputingPower: 2, Operations: []string{"+", "-", "*", "/"}})
+	o.AddExpression("1+1")
+	solve(o)
+	o.AddExpression("2*3")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{"eval", []string{"eval", "3+3"}, 0, "3\n", ""},
+		{"eval с минусом в начале", []string{"eval", "-2+3"}, 0, "4\n", ""},
+		{"eval после --", []string{"eval", "--", "-5*2"}, 0, "5\n", ""},
+		{"eval в JSON", []string{"eval", "-o", "json", "4+4"}, 0, `"id": 6`, ""},
+		{"list", []string{"list", "--status", "done"}, 0, "1+1", ""},
+		{"list в JSON", []string{"list", "--sort", "-id", "--limit", "1", "-o", "json"}, 0, `"next_cursor"`, ""},
+		{"get", []string{"get", "1"}, 0, "done", ""},
+		{"get с задачами", []string{"get", "1", "--tasks"}, 0, "1 + 1", ""},
+		{"cancel", []string{"cancel", "2"}, 0, "cancelled", ""},
+		{"повторный cancel", []string{"cancel", "2"}, 1, "", "409"},
+		{"agents", []string{"agents"}, 0, "host-1", ""},
+		{"Выражение не найдено", []string{"get", "42"}, 1, "", "404"},
+		{"Неверное выражение", []string{"eval", "2++2"}, 1, "", "422"},
+		{"Неизвестная команда", []string{"solve"}, 2, "", "Неизвестная команда"},
+		{"Нет аргумента", []string{"get"}, 2, "", "ожидает аргументов: 1"},
+		{"Неверный ID", []string{"cancel", "x"}, 2, "", "неверный ID"},
+		{"Неизвестный флаг", []string{"list", "--bogus"}, 2, "", "bogus"},
+		{"Неизвестный формат", []string{"agents", "-o", "yaml"}, 2, "", "yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{tt.args[0], "--url", srv.URL}, tt.args[1:]...)
+			code := run(args, &stdout, &stderr)
+
+			if code != tt.wantCode {
+				t.Fatalf("❌ %s: ожидали код %d, а получили %d: %s", tt.name, tt.wantCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Fatalf("❌ %s: ожидали в выводе %q, а получили %q", tt.name, tt.wantStdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Fatalf("❌ %s: ожидали в ошибке %q, а получили %q", tt.name, tt.wantStderr, stderr.String())
+			}
+			fmt.Printf("✅ %s: код %d\n", tt.name, code)
+		})
+	}
+}
+
+func TestEvalWait(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	defer srv.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		solve(o)
+	}()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"eval", "-2+2*4", "--wait", "--timeout", "2s", "--url", srv.URL}, &stdout, &stderr)
+	if code != 0 || !strings.Contains(stdout.String(), "done") || !strings.Contains(stdout.String(), " 6 ") {
+		t.Fatalf("❌ ожидали результат 6, а получили код %d: %s %s", code, stdout.String(), stderr.String())
+	}
+	fmt.Println("✅ eval --wait дождался результата")
+
+	// Выражение, которое никто не вычисляет, не дожидается результата до таймаута.
+	stdout.Reset()
+	code = run([]string{"eval", "1+1", "--wait", "--timeout", "100ms", "--url", srv.URL}, &stdout, &stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "deadline exceeded") {
+		t.Fatalf("❌ ожидали ошибку таймаута, а получили код %d: %s", code, stderr.String())
+	}
+	fmt.Println("✅ eval --wait завершился по таймауту")
+}
@@ -1,15 +1,38 @@
 package main
 
 import (
-	"Calc_2GO/Internal/orchestrator"
-	"log"
+	"Calc_2GO/internal/orchestrator"
+	"Calc_2GO/pkg/logging"
+	"Calc_2GO/pkg/tracing"
+	"context"
+	"log/slog"
+	"os"
 )
 
 func main() {
+	logger := logging.New(os.Stderr)
+
+	// Ненулевой код выхода нужен супервизору, чтобы перезапустить оркестратор и поднять тревогу
+	if err := run(logger); err != nil {
+		logger.Error("оркестратор остановлен с ошибкой", logging.Err(err))
+		os.Exit(1)
+	}
+}
+
+// run запускает оркестратор и возвращает ошибку, с которой он остановился.
+func run(logger *slog.Logger) error {
+	// Трассировки отправляются по OTLP, если задан OTEL_EXPORTER_OTLP_ENDPOINT
+	shutdown, err := tracing.Setup(context.Background(), "calc-orchestrator")
+	if err != nil {
+		logger.Error("ошибка настройки трассировки", logging.Err(err))
+	} else {
+		defer shutdown(context.Background())
+	}
+
 	// Создаем новый оркестратор
-	o := orchestrator.NewOrchestrator()
+	o := orchestrator.NewOrchestrator(orchestrator.WithLogger(logger))
 
 	// Запускаем сервер оркестратора
-	log.Println("🛠️ Запуск оркестратора...")
-	o.StartServer()
+	logger.Info("запуск оркестратора")
+	return o.StartServer()
 }
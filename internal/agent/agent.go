@@ -1,31 +1,62 @@
 package agent
 
 import (
-	models "Calc_2GO/Models"
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/logging"
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
-	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Version — версия агента, которую он сообщает оркестратору при регистрации.
+const Version = "1.1.0"
+
 type Agent struct {
+	id                 string
+	hostname           string
+	token              string
 	orchestratorURL    string
 	computingPower     int
 	timeAddition       time.Duration
 	timeSubtraction    time.Duration
 	timeMultiplication time.Duration
 	timeDivision       time.Duration
-	logger             *log.Logger
+	logger             *slog.Logger
+	tracer             trace.Tracer
+	client             *http.Client
+	httpAddr           string
+	connected          atomic.Bool
+	metrics            *agentMetrics
+	heartbeatInterval  time.Duration
+	retry              RetryPolicy
 	taskQueue          chan *models.Task
-	wg                 sync.WaitGroup
+	slots              chan struct{}
+	breaker            *breaker
+	outbox             *outbox
+	// configErr — ошибка настройки из переменных среды, с которой агент не запускается.
+	configErr error
 }
 
-func NewAgent(orchestratorURL string, computingPower int) *Agent {
+// Option настраивает агента при создании.
+type Option func(*Agent)
+
+// WithLogger задаёт логгер агента. По умолчанию логгер настраивается
+// переменными LOG_LEVEL и LOG_FORMAT и пишет в stderr.
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Agent) {
+		a.logger = logger
+	}
+}
+
+func NewAgent(orchestratorURL string, computingPower int, opts ...Option) *Agent {
 	os.Setenv("TIME_ADDITION_MS", "10_000")
 	os.Setenv("TIME_SUBTRACTION_MS", "10_000")
 	os.Setenv("TIME_MULTIPLICATION_MS", "10_000")
@@ -35,91 +66,212 @@ func NewAgent(orchestratorURL string, computingPower int) *Agent {
 		computingPower = 1
 	}
 
-	logger := log.New(os.Stdout, "[AGENT] ", log.LstdFlags)
-
 	timeAddition := getEnvDuration("TIME_ADDITION_MS")
 	timeSubtraction := getEnvDuration("TIME_SUBTRACTION_MS")
 	timeMultiplication := getEnvDuration("TIME_MULTIPLICATION_MS")
 	timeDivision := getEnvDuration("TIME_DIVISION_MS")
 
-	return &Agent{
+	hostname, _ := os.Hostname()
+	id := os.Getenv("AGENT_ID")
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	heartbeatInterval := 5 * time.Second
+	if ms, err := strconv.Atoi(os.Getenv("AGENT_HEARTBEAT_MS")); err == nil && ms > 0 {
+		heartbeatInterval = time.Duration(ms) * time.Millisecond
+	}
+
+	a := &Agent{
+		id:                 id,
+		hostname:           hostname,
+		token:              os.Getenv("AGENT_TOKEN"),
 		orchestratorURL:    orchestratorURL,
 		computingPower:     computingPower,
 		timeAddition:       timeAddition,
 		timeSubtraction:    timeSubtraction,
 		timeMultiplication: timeMultiplication,
 		timeDivision:       timeDivision,
-		logger:             logger,
+		logger:             logging.New(os.Stderr),
+		tracer:             defaultTracer(),
+		httpAddr:           os.Getenv("AGENT_HTTP_ADDR"),
+		metrics:            newAgentMetrics(),
+		heartbeatInterval:  heartbeatInterval,
+		retry:              retryPolicyFromEnv(),
 		taskQueue:          make(chan *models.Task, computingPower),
+		slots:              make(chan struct{}, computingPower),
+		outbox:             newOutbox(getEnvInt("AGENT_OUTBOX_SIZE", 10_000)),
+	}
+	a.breaker = newBreaker(breakerConfigFromEnv(), a.onCircuitChange)
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.logger = a.logger.With(logging.AgentID(id))
+
+	// Без настроенного TLS агент не обращается к оркестратору: запрос без
+	// клиентского сертификата или с недоверенным CA не должен уйти молча.
+	a.client, a.configErr = newHTTPClient()
+	if a.configErr != nil {
+		a.configErr = fmt.Errorf("ошибка настройки TLS: %w", a.configErr)
 	}
+
+	return a
+}
+
+// ID возвращает идентификатор агента.
+func (a *Agent) ID() string {
+	return a.id
 }
 
-func (a *Agent) Start() {
+// Start регистрирует агента и запускает воркеры. Возвращает ошибку, если агент
+// настроен неверно и не может обращаться к оркестратору.
+func (a *Agent) Start() error {
+	if a.configErr != nil {
+		return a.configErr
+	}
+	if err := a.Register(); err != nil {
+		a.logger.Error("ошибка регистрации агента", logging.Err(err))
+	}
+	go a.heartbeatLoop()
+	if a.httpAddr != "" {
+		go a.serveHTTP()
+	}
+
 	for i := 0; i < a.computingPower; i++ {
 		go a.worker(i)
 	}
 
 	go a.taskDispatcher()
+	go a.resultSubmitter()
+	return nil
 }
 
+// taskDispatcher запрашивает у оркестратора столько задач, сколько сейчас свободных воркеров.
+// После ошибок запроса пауза растёт по политике повторов и сбрасывается после успешного запроса.
 func (a *Agent) taskDispatcher() {
+	failures := 0
 	for {
-		task, err := a.getTask()
+		// Ждём хотя бы одного свободного воркера.
+		a.slots <- struct{}{}
+		free := 1
+		for free < a.computingPower && len(a.slots) < cap(a.slots) {
+			a.slots <- struct{}{}
+			free++
+		}
+
+		tasks, err := a.getTasks(free)
+		if errors.Is(err, ErrCircuitOpen) {
+			a.releaseSlots(free)
+			a.waitCircuit()
+			continue
+		}
+		if errors.Is(err, errNoTasks) {
+			failures = 0
+			a.releaseSlots(free)
+			time.Sleep(idleInterval)
+			continue
+		}
 		if err != nil {
-			a.logger.Printf("❌ ошибка при получении задачи: %v\n", err) // Исправлено
-			time.Sleep(2 * time.Second)
+			failures++
+			wait := a.retry.backoff(failures)
+			a.logger.Warn("ошибка при получении задач", "attempt", failures, "retry_in", wait, logging.Err(err))
+			a.metrics.fetchErrors.Inc()
+			a.releaseSlots(free)
+			time.Sleep(wait)
 			continue
 		}
+		failures = 0
 
-		a.wg.Add(1)
-		a.taskQueue <- task
-		a.wg.Wait()
+		a.releaseSlots(free - len(tasks))
+		for _, task := range tasks {
+			a.taskQueue <- task
+		}
+	}
+}
+
+func (a *Agent) releaseSlots(n int) {
+	for i := 0; i < n; i++ {
+		<-a.slots
 	}
 }
 
 func (a *Agent) worker(id int) {
 	for task := range a.taskQueue {
-		a.logger.Printf("✅ Агент №%d результат задачи %d успешно отправлен:", id, task.ID)
-
+		a.metrics.busyWorkers.Inc()
+		start := time.Now()
 		result, err := a.ExecuteTask(task)
+		computeTime := time.Since(start)
+		a.metrics.busyWorkers.Dec()
+		a.metrics.taskDuration.WithLabelValues(task.Operation).Observe(computeTime.Seconds())
 		if err != nil {
-			a.logger.Printf("❌ ошибка при выполнении задачи %d: %v\n", task.ID, err) // Исправлено
-			a.taskQueue <- task
+			// Ошибка выполнения повторится при любой попытке: сообщаем о ней оркестратору.
+			a.metrics.tasksExecuted.WithLabelValues(task.Operation, "error").Inc()
+			a.logger.Warn("ошибка при выполнении задачи", logging.TaskID(task.ID), logging.ExpressionID(task.ExpressionID), logging.Operation(task.Operation), logging.Err(err))
+			a.complete(models.TaskResult{ID: task.ID, ComputeTime: computeTime, Error: err.Error()})
+			a.releaseSlots(1)
 			continue
 		}
 
-		if err := a.submitTaskResult(task.ID, result); err != nil {
-			a.logger.Printf("❌ ошибка при отправке результата задачи %d: %v\n", task.ID, err) // Исправлено
-			a.taskQueue <- task
-		} else {
-			a.logger.Printf("✅ Агент №%d результат задачи %d успешно отправлен: %f\n", id, task.ID, result)
-		}
-
-		a.wg.Done()
+		a.metrics.tasksExecuted.WithLabelValues(task.Operation, "ok").Inc()
+		a.logger.Info("задача выполнена", "worker", id, logging.TaskID(task.ID), logging.ExpressionID(task.ExpressionID), logging.Operation(task.Operation), logging.Duration(computeTime), "result", result)
+		a.complete(models.TaskResult{ID: task.ID, Result: result, ComputeTime: computeTime})
+		a.releaseSlots(1)
 	}
 }
 
-func (a *Agent) getTask() (*models.Task, error) {
-	resp, err := http.Get(a.orchestratorURL + "/internal/task")
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при запросе задачи: %w", err) // Исправлено
-	}
-	defer resp.Body.Close()
+// complete кладёт результат задачи в outbox, откуда его отправит resultSubmitter.
+func (a *Agent) complete(res models.TaskResult) {
+	a.outbox.put(res)
+	a.metrics.outboxResults.Set(float64(a.outbox.len()))
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("задачи недоступны, код ответа: %d", resp.StatusCode) // Исправлено
-	}
+// resultSubmitter отправляет результаты из outbox пачками: всё, что накопилось
+// к моменту отправки, уходит одним запросом. Результаты удаляются из outbox,
+// только когда оркестратор ответил: временные ошибки повторяются по политике
+// повторов, а пока цепь разомкнута или оркестратор не принимает токен агента,
+// результаты ждут в outbox.
+func (a *Agent) resultSubmitter() {
+	for {
+		batch := a.outbox.next(models.MaxBatchSize)
 
-	var task models.Task
-	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
-		return nil, fmt.Errorf("ошибка при декодировании задачи: %w", err) // Исправлено
+		attempt := 0
+		err := a.retry.do(func() error {
+			attempt++
+			err := a.submitResults(batch)
+			if err != nil && !errors.Is(err, ErrCircuitOpen) {
+				a.logger.Warn("ошибка при отправке результатов", "results", len(batch), "attempt", attempt, logging.Err(err))
+				a.metrics.submitErrors.Inc()
+			}
+			return err
+		})
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			a.waitCircuit()
+			continue
+		case retryable(err):
+			a.logger.Error("оркестратор недоступен, отправка результатов отложена", "results", a.outbox.len(), "attempts", attempt, "retry_in", a.retry.MaxBackoff, logging.Err(err))
+			time.Sleep(a.retry.MaxBackoff)
+			continue
+		case unauthorized(err):
+			a.logger.Error("оркестратор не принимает токен агента, отправка результатов отложена", "results", a.outbox.len(), "retry_in", a.retry.MaxBackoff, logging.Err(err))
+			time.Sleep(a.retry.MaxBackoff)
+			continue
+		case err != nil:
+			a.logger.Error("результаты отклонены оркестратором", "results", len(batch), logging.Err(err))
+		}
+
+		a.outbox.ack(len(batch))
+		a.metrics.outboxResults.Set(float64(a.outbox.len()))
 	}
-	a.logger.Printf("задача %d получена", task.ID)
-	return &task, nil
 }
 
-func (a *Agent) ExecuteTask(task *models.Task) (float64, error) {
-	a.logger.Printf("выполнение задачи %d: %f %s %f", task.ID, task.Arg1, task.Operation, task.Arg2)
+// waitCircuit ждёт, пока разомкнутая цепь станет полуоткрытой.
+func (a *Agent) waitCircuit() {
+	time.Sleep(max(a.breaker.retryIn(), a.retry.InitialBackoff))
+}
+
+func (a *Agent) execute(task *models.Task) (float64, error) {
+	a.logger.Debug("выполнение задачи", logging.TaskID(task.ID), logging.Operation(task.Operation), "arg1", task.Arg1, "arg2", task.Arg2)
 
 	var operationTime time.Duration
 	switch task.Operation {
@@ -133,7 +285,7 @@ func (a *Agent) ExecuteTask(task *models.Task) (float64, error) {
 		operationTime = a.timeDivision
 
 	default:
-		return 0, fmt.Errorf("неизвестная операция: %s", task.Operation)
+		return 0, fmt.Errorf("%w: %s", ErrUnknownOperation, task.Operation)
 	}
 
 	time.Sleep(operationTime)
@@ -148,39 +300,33 @@ func (a *Agent) ExecuteTask(task *models.Task) (float64, error) {
 		result = task.Arg1 * task.Arg2
 	case "/":
 		if task.Arg2 == 0 {
-			return 0, fmt.Errorf("деление на ноль")
+			return 0, ErrDivisionByZero
 		}
 		result = task.Arg1 / task.Arg2
 	default:
-		return 0, fmt.Errorf("неизвестная операция: %s", task.Operation)
+		return 0, fmt.Errorf("%w: %s", ErrUnknownOperation, task.Operation)
 	}
 
-	a.logger.Printf("✅ задача %d выполнена, результат: %f", task.ID, result)
 	return result, nil
 }
 
-func (a *Agent) submitTaskResult(taskID int, result float64) error {
-	req := struct {
-		ID     int     `json:"id"`
-		Result float64 `json:"result"`
-	}{
-		ID:     taskID,
-		Result: result,
+// do выполняет запрос к оркестратору от имени агента.
+func (a *Agent) do(method, path string, body []byte) (*http.Response, error) {
+	if a.configErr != nil {
+		return nil, a.configErr
 	}
-
-	reqBody, _ := json.Marshal(req)
-	a.logger.Printf("отправка результата задачи %d: %f", taskID, result)
-
-	resp, err := http.Post(a.orchestratorURL+"/internal/task", "application/json", bytes.NewBuffer(reqBody))
+	req, err := http.NewRequest(method, a.orchestratorURL+path, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("ошибка при отправке результата: %w", err) // Исправлено
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("не удалось отправить результат, код ответа: %d", resp.StatusCode) // Исправлено
+	req.Header.Set(models.AgentIDHeader, a.id)
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
 	}
-	return nil
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return a.client.Do(req)
 }
 
 func getEnvDuration(key string) time.Duration {
@@ -1,8 +1,8 @@
 package agent_test
 
 import (
-	models "Calc_2GO/Models"
 	"Calc_2GO/internal/agent"
+	models "Calc_2GO/models"
 	"encoding/json" // Добавлен импорт
 	"fmt"
 	"net/http"
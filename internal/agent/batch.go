@@ -0,0 +1,73 @@
+package agent
+
+import (
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// errNoTasks — у оркестратора нет задач, которые агент может выполнить.
+var errNoTasks = errors.New("нет доступных задач")
+
+// getTasks запрашивает у оркестратора до limit задач одним запросом.
+func (a *Agent) getTasks(limit int) ([]*models.Task, error) {
+	resp, err := a.call(http.MethodGet, "/internal/task?limit="+strconv.Itoa(limit), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе задачи: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errNoTasks
+	default:
+		return nil, fmt.Errorf("задачи недоступны: %w", &statusError{resp.StatusCode})
+	}
+
+	var response struct {
+		Tasks []*models.Task `json:"tasks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("ошибка при декодировании задачи: %w", err)
+	}
+	a.logger.Debug("получены задачи", "count", len(response.Tasks))
+	return response.Tasks, nil
+}
+
+// submitResults отправляет пачку результатов и возвращает подтверждения по каждому из них.
+// Результаты, которые оркестратор отклонил, повторно не отправляются.
+func (a *Agent) submitResults(results []models.TaskResult) error {
+	reqBody, _ := json.Marshal(struct {
+		Results []models.TaskResult `json:"results"`
+	}{results})
+	a.logger.Debug("отправка результатов", "count", len(results))
+
+	resp, err := a.call(http.MethodPost, "/internal/task", reqBody)
+	if err != nil {
+		return fmt.Errorf("ошибка при отправке результата: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("не удалось отправить результат: %w", &statusError{resp.StatusCode})
+	}
+
+	var response struct {
+		Acks []models.Ack `json:"acks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("ошибка при декодировании ответа: %w", err)
+	}
+
+	for _, ack := range response.Acks {
+		if ack.Status != models.AckOK {
+			a.logger.Warn("результат задачи отклонён", logging.TaskID(ack.ID), "error", ack.Error)
+		}
+	}
+	return nil
+}
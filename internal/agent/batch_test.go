@@ -0,0 +1,86 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgentBatches(t *testing.T) {
+	tasks := []models.Task{
+		{ID: 1, Arg1: 1, Arg2: 2, Operation: "+"},
+		{ID: 2, Arg1: 3, Arg2: 4, Operation: "*"},
+		{ID: 3, Arg1: 9, Arg2: 3, Operation: "/"},
+	}
+	want := map[int]float64{1: 3, 2: 12, 3: 3}
+
+	var mu sync.Mutex
+	var limits []string
+	results := make(chan map[int]float64, len(tasks))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/internal/agents":
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/internal/task" && r.Method == http.MethodGet:
+			mu.Lock()
+			defer mu.Unlock()
+			limits = append(limits, r.URL.Query().Get("limit"))
+			if len(limits) > 1 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]models.Task{"tasks": tasks})
+		case r.URL.Path == "/internal/task" && r.Method == http.MethodPost:
+			var batch struct {
+				Results []models.TaskResult `json:"results"`
+			}
+			json.NewDecoder(r.Body).Decode(&batch)
+
+			got := make(map[int]float64)
+			acks := make([]map[string]any, 0, len(batch.Results))
+			for _, res := range batch.Results {
+				got[res.ID] = res.Result
+				acks = append(acks, map[string]any{"id": res.ID, "status": "ok"})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"acks": acks})
+			results <- got
+		}
+	}))
+	defer ts.Close()
+
+	agent.NewAgent(ts.URL, 3).Start()
+
+	got := make(map[int]float64)
+	requests := 0
+	for len(got) < len(tasks) {
+		select {
+		case batch := <-results:
+			requests++
+			for id, result := range batch {
+				got[id] = result
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("❌ получены не все результаты: %v", got)
+		}
+	}
+
+	for id, result := range want {
+		if got[id] != result {
+			t.Fatalf("❌ задача %d: ожидали %g, а получили %g", id, result, got[id])
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if limits[0] != "3" {
+		t.Fatalf("❌ ожидали запрос 3 задач, а получили limit=%s", limits[0])
+	}
+	fmt.Printf("✅ %d задачи получены одним запросом, результаты отправлены за %d запроса(ов)\n", len(tasks), requests)
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen — запрос не отправлен: оркестратор недоступен, и агент
+// ждёт, прежде чем снова к нему обратиться.
+var ErrCircuitOpen = errors.New("оркестратор недоступен, запросы приостановлены")
+
+// Состояния автомата защиты.
+const (
+	circuitClosed   = "closed"
+	circuitHalfOpen = "half_open"
+	circuitOpen     = "open"
+)
+
+// circuitStateValue — значение метрики calc_agent_circuit_state для состояния.
+var circuitStateValue = map[string]float64{circuitClosed: 0, circuitHalfOpen: 1, circuitOpen: 2}
+
+// BreakerConfig — настройки автомата защиты запросов к оркестратору.
+type BreakerConfig struct {
+	// Threshold — сколько временных ошибок подряд размыкают цепь.
+	Threshold int
+	// OpenTimeout — сколько цепь остаётся разомкнутой, прежде чем агент
+	// отправит пробный запрос.
+	OpenTimeout time.Duration
+}
+
+// WithCircuitBreaker задаёт настройки автомата защиты. По умолчанию они
+// берутся из переменных AGENT_BREAKER_THRESHOLD и AGENT_BREAKER_OPEN_MS.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(a *Agent) {
+		a.breaker.BreakerConfig = cfg
+	}
+}
+
+// breakerConfigFromEnv возвращает настройки по умолчанию: цепь размыкается
+// после 5 ошибок подряд на 10 секунд.
+func breakerConfigFromEnv() BreakerConfig {
+	return BreakerConfig{
+		Threshold:   getEnvInt("AGENT_BREAKER_THRESHOLD", 5),
+		OpenTimeout: time.Duration(getEnvInt("AGENT_BREAKER_OPEN_MS", 10_000)) * time.Millisecond,
+	}
+}
+
+// breaker — автомат защиты: пока цепь замкнута, запросы проходят; после
+// Threshold временных ошибок подряд цепь размыкается, и запросы не отправляются
+// OpenTimeout; затем один пробный запрос (полуоткрытое состояние) либо
+// замыкает цепь, либо снова её размыкает.
+type breaker struct {
+	BreakerConfig
+	// onChange вызывается при смене состояния.
+	onChange func(from, to string)
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// probing — пробный запрос уже отправлен.
+	probing bool
+}
+
+func newBreaker(cfg BreakerConfig, onChange func(from, to string)) *breaker {
+	return &breaker{BreakerConfig: cfg, onChange: onChange, state: circuitClosed}
+}
+
+// allow сообщает, можно ли отправить запрос.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+	case circuitHalfOpen:
+	default:
+		return nil
+	}
+
+	if b.probing {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// record учитывает исход запроса: сетевые ошибки, 429 и 5xx — отказ
+// оркестратора, любой другой ответ означает, что оркестратор доступен.
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !retryable(err) {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= max(b.Threshold, 1) {
+		b.openedAt = time.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// retryIn возвращает, через сколько цепь станет полуоткрытой.
+func (b *breaker) retryIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(b.OpenTimeout-time.Since(b.openedAt), 0)
+}
+
+// setState меняет состояние. Вызывается под b.mu.
+func (b *breaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
+
+// onCircuitChange записывает смену состояния автомата защиты в логи и метрики.
+func (a *Agent) onCircuitChange(from, to string) {
+	a.metrics.circuitState.Set(circuitStateValue[to])
+	a.metrics.circuitTransitions.WithLabelValues(to).Inc()
+	if to == circuitOpen {
+		a.logger.Warn("оркестратор недоступен, запросы приостановлены", "from", from, "to", to, "open_for", a.breaker.OpenTimeout)
+		return
+	}
+	a.logger.Info("состояние автомата защиты изменилось", "from", from, "to", to)
+}
+
+// call выполняет запрос к оркестратору через автомат защиты. Ответ 429 или 5xx
+// возвращается вызывающему, но учитывается как отказ оркестратора.
+func (a *Agent) call(method, path string, body []byte) (*http.Response, error) {
+	if err := a.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.do(method, path, body)
+	if err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) {
+		a.breaker.record(&statusError{resp.StatusCode})
+	} else {
+		a.breaker.record(err)
+	}
+	return resp, err
+}
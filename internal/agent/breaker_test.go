@@ -0,0 +1,90 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAgentCircuitBreaker(t *testing.T) {
+	var down, fetched atomic.Bool
+	var failed atomic.Int32
+	results := make(chan models.TaskResult, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/internal/agents":
+			w.WriteHeader(http.StatusCreated)
+		case down.Load():
+			failed.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Method == http.MethodGet && fetched.Load():
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			// Оркестратор выдаёт задачу и становится недоступен.
+			fetched.Store(true)
+			down.Store(true)
+			json.NewEncoder(w).Encode(map[string][]models.Task{"tasks": {{ID: 1, Arg1: 2, Arg2: 2, Operation: "+"}}})
+		case r.Method == http.MethodPost:
+			var batch struct {
+				Results []models.TaskResult `json:"results"`
+			}
+			json.NewDecoder(r.Body).Decode(&batch)
+			json.NewEncoder(w).Encode(map[string]any{"acks": []map[string]any{{"id": 1, "status": "ok"}}})
+			for _, res := range batch.Results {
+				results <- res
+			}
+		}
+	}))
+	defer ts.Close()
+
+	ag := agent.NewAgent(ts.URL, 1,
+		agent.WithRetryPolicy(fastRetry),
+		agent.WithCircuitBreaker(agent.BreakerConfig{Threshold: 2, OpenTimeout: 100 * time.Millisecond}))
+	ag.Start()
+
+	time.Sleep(300 * time.Millisecond)
+	// Без автомата защиты агент отправил бы десятки запросов с паузами до 10 мс.
+	if n := failed.Load(); n > 8 {
+		t.Fatalf("❌ агент продолжает отправлять запросы недоступному оркестратору: %d", n)
+	}
+	fmt.Printf("✅ Пока оркестратор недоступен, отправлено %d запросов\n", failed.Load())
+
+	metrics := func() string {
+		rec := httptest.NewRecorder()
+		ag.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Body.String()
+	}
+	if body := metrics(); !containsAll(body, []string{`calc_agent_circuit_transitions_total{state="open"}`, "calc_agent_outbox_results 1"}) {
+		t.Fatalf("❌ ожидали разомкнутую цепь и результат в outbox:\n%s", body)
+	}
+
+	down.Store(false)
+	select {
+	case res := <-results:
+		if res.ID != 1 || res.Result != 4 {
+			t.Fatalf("❌ неожиданный результат %+v", res)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("❌ результат из outbox не доставлен")
+	}
+	fmt.Println("✅ Результат доставлен после восстановления оркестратора")
+
+	want := []string{`calc_agent_circuit_state 0`, `calc_agent_circuit_transitions_total{state="closed"}`, "calc_agent_outbox_results 0"}
+	var body string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if body = metrics(); containsAll(body, want) {
+			break
+		}
+	}
+	if !containsAll(body, want) {
+		t.Fatalf("❌ ожидали замкнутую цепь и пустой outbox:\n%s", body)
+	}
+	fmt.Println("✅ Цепь снова замкнута")
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"Calc_2GO/pkg/buildinfo"
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Ready сообщает, связался ли агент с оркестратором: он зарегистрирован
+// и последний heartbeat принят.
+func (a *Agent) Ready() bool {
+	return a.connected.Load()
+}
+
+// Handler возвращает HTTP-обработчик агента: /metrics, /healthz, /readyz и /version.
+func (a *Agent) Handler() http.Handler {
+	info := buildinfo.Get()
+	info.Version = Version
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(a.metrics.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", a.handleReadyz)
+	mux.HandleFunc("/version", buildinfo.Handler(info))
+	return mux
+}
+
+func (a *Agent) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !a.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "not_ready", "reason": "нет связи с оркестратором"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
+
+// serveHTTP запускает HTTP-сервер агента на AGENT_HTTP_ADDR.
+func (a *Agent) serveHTTP() {
+	a.logger.Info("HTTP-сервер агента запущен", "addr", a.httpAddr)
+	if err := http.ListenAndServe(a.httpAddr, a.Handler()); err != nil {
+		a.logger.Error("ошибка HTTP-сервера агента", logging.Err(err))
+	}
+}
@@ -0,0 +1,61 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAgentReadiness(t *testing.T) {
+	orchestratorUp := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !orchestratorUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/internal/agents":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	ag := agent.NewAgent(ts.URL, 1)
+	readyz := func() int {
+		rec := httptest.NewRecorder()
+		ag.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	steps := []struct {
+		name           string
+		orchestratorUp bool
+		wantStatus     int
+	}{
+		{"Оркестратор недоступен", false, http.StatusServiceUnavailable},
+		{"Агент связался с оркестратором", true, http.StatusOK},
+		{"Связь потеряна", false, http.StatusServiceUnavailable},
+	}
+
+	for _, step := range steps {
+		orchestratorUp = step.orchestratorUp
+		ag.SendHeartbeat()
+		if got := readyz(); got != step.wantStatus {
+			t.Fatalf("❌ %s: ожидали /readyz %d, а получили %d", step.name, step.wantStatus, got)
+		}
+		fmt.Printf("✅ %s: /readyz %d\n", step.name, step.wantStatus)
+	}
+
+	rec := httptest.NewRecorder()
+	ag.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var info map[string]any
+	json.NewDecoder(rec.Body).Decode(&info)
+	if info["version"] != agent.Version {
+		t.Fatalf("❌ /version: ожидали версию %s, а получили %v", agent.Version, info)
+	}
+}
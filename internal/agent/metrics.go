@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// agentMetrics — метрики агента, отдаваемые на /metrics.
+type agentMetrics struct {
+	registry *prometheus.Registry
+
+	busyWorkers   prometheus.Gauge
+	tasksExecuted *prometheus.CounterVec
+	taskDuration  *prometheus.HistogramVec
+	fetchErrors   prometheus.Counter
+	submitErrors  prometheus.Counter
+
+	circuitState       prometheus.Gauge
+	circuitTransitions *prometheus.CounterVec
+	outboxResults      prometheus.Gauge
+}
+
+// newAgentMetrics создаёт метрики в собственном реестре вместе с метриками
+// среды выполнения Go и процесса.
+func newAgentMetrics() *agentMetrics {
+	r := prometheus.NewRegistry()
+	r.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	f := promauto.With(r)
+	return &agentMetrics{
+		registry: r,
+		busyWorkers: f.NewGauge(prometheus.GaugeOpts{
+			Name: "calc_agent_busy_workers", Help: "Количество воркеров, выполняющих задачу.",
+		}),
+		tasksExecuted: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "calc_agent_tasks_executed_total", Help: "Выполненные задачи по операциям и результату.",
+		}, []string{"operation", "status"}),
+		taskDuration: f.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "calc_agent_task_duration_seconds", Help: "Время выполнения задачи.",
+			// Время операций настраивается и может превышать 10 секунд
+			Buckets: slices.Concat(prometheus.DefBuckets, []float64{30, 60}),
+		}, []string{"operation"}),
+		fetchErrors: f.NewCounter(prometheus.CounterOpts{
+			Name: "calc_agent_fetch_errors_total", Help: "Ошибки получения задач от оркестратора.",
+		}),
+		submitErrors: f.NewCounter(prometheus.CounterOpts{
+			Name: "calc_agent_submit_errors_total", Help: "Ошибки отправки результатов оркестратору.",
+		}),
+
+		circuitState: f.NewGauge(prometheus.GaugeOpts{
+			Name: "calc_agent_circuit_state", Help: "Состояние автомата защиты: 0 — замкнут, 1 — полуоткрыт, 2 — разомкнут.",
+		}),
+		circuitTransitions: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "calc_agent_circuit_transitions_total", Help: "Переходы автомата защиты по новому состоянию.",
+		}, []string{"state"}),
+		outboxResults: f.NewGauge(prometheus.GaugeOpts{
+			Name: "calc_agent_outbox_results", Help: "Результаты, ожидающие отправки оркестратору.",
+		}),
+	}
+}
@@ -0,0 +1,78 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAgentMetrics(t *testing.T) {
+	var fetches atomic.Int32
+	submitted := make(chan struct{}, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/internal/agents":
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/internal/task" && r.Method == http.MethodGet:
+			if fetches.Add(1) > 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]models.Task{"tasks": {{ID: 1, Arg1: 1, Arg2: 2, Operation: "+"}}})
+		case r.URL.Path == "/internal/task" && r.Method == http.MethodPost:
+			json.NewEncoder(w).Encode(map[string]any{"acks": []map[string]any{{"id": 1, "status": "ok"}}})
+			submitted <- struct{}{}
+		}
+	}))
+	defer ts.Close()
+
+	ag := agent.NewAgent(ts.URL, 1)
+	ag.Start()
+
+	select {
+	case <-submitted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("❌ результат не отправлен")
+	}
+
+	want := []string{
+		`calc_agent_busy_workers 0`,
+		`calc_agent_tasks_executed_total{operation="+",status="ok"} 1`,
+		`calc_agent_task_duration_seconds_count{operation="+"} 1`,
+		`calc_agent_fetch_errors_total 1`,
+		`go_goroutines`,
+		`process_resident_memory_bytes`,
+	}
+
+	var body string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		rec := httptest.NewRecorder()
+		ag.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body = rec.Body.String()
+		if containsAll(body, want) {
+			break
+		}
+	}
+
+	if !containsAll(body, want) {
+		t.Fatalf("❌ В метриках агента нет ожидаемых строк %v:\n%s", want, body)
+	}
+	fmt.Println("✅ Метрики агента обновляются")
+}
+
+func containsAll(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	models "Calc_2GO/models"
+	"slices"
+	"sync"
+)
+
+// outbox — очередь выполненных задач, ещё не принятых оркестратором. Пока
+// оркестратор недоступен, результаты копятся в ней и не теряются. Если очередь
+// заполнена, воркеры ждут, пока освободится место.
+type outbox struct {
+	limit int
+	// ready — сигнал отправителю, что в очереди появились результаты.
+	ready chan struct{}
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	results []models.TaskResult
+}
+
+// newOutbox создаёт очередь на limit результатов.
+func newOutbox(limit int) *outbox {
+	b := &outbox{limit: max(limit, 1), ready: make(chan struct{}, 1)}
+	b.notFull = sync.NewCond(&b.mu)
+	return b
+}
+
+// put добавляет результат в конец очереди.
+func (b *outbox) put(res models.TaskResult) {
+	b.mu.Lock()
+	for len(b.results) >= b.limit {
+		b.notFull.Wait()
+	}
+	b.results = append(b.results, res)
+	b.mu.Unlock()
+
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next возвращает до n первых результатов, дожидаясь хотя бы одного.
+// Результаты остаются в очереди, пока их не подтвердит ack.
+func (b *outbox) next(n int) []models.TaskResult {
+	for {
+		b.mu.Lock()
+		if len(b.results) > 0 {
+			batch := slices.Clone(b.results[:min(n, len(b.results))])
+			b.mu.Unlock()
+			return batch
+		}
+		b.mu.Unlock()
+		<-b.ready
+	}
+}
+
+// ack удаляет из очереди n первых результатов.
+func (b *outbox) ack(n int) {
+	b.mu.Lock()
+	b.results = b.results[n:]
+	b.mu.Unlock()
+	b.notFull.Broadcast()
+}
+
+// len возвращает количество результатов в очереди.
+func (b *outbox) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.results)
+}
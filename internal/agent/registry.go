@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// operations — операции, которые умеет выполнять агент.
+var operations = []string{"+", "-", "*", "/"}
+
+// Register регистрирует агента в оркестраторе.
+func (a *Agent) Register() error {
+	reqBody, _ := json.Marshal(struct {
+		ID             string   `json:"id"`
+		Hostname       string   `json:"hostname"`
+		ComputingPower int      `json:"computing_power"`
+		Operations     []string `json:"operations"`
+		Version        string   `json:"version"`
+	}{
+		ID:             a.id,
+		Hostname:       a.hostname,
+		ComputingPower: a.computingPower,
+		Operations:     operations,
+		Version:        Version,
+	})
+
+	resp, err := a.do(http.MethodPost, "/internal/agents", reqBody)
+	if err != nil {
+		return fmt.Errorf("ошибка при регистрации: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("не удалось зарегистрироваться, код ответа: %d", resp.StatusCode)
+	}
+
+	a.logger.Info("агент зарегистрирован", "operations", operations, "computing_power", a.computingPower)
+	a.connected.Store(true)
+	return nil
+}
+
+// SendHeartbeat сообщает оркестратору, что агент жив. Если оркестратор
+// не знает агента (например, после перезапуска), агент регистрируется заново.
+// Пока heartbeat не принимается, агент не готов (/readyz).
+func (a *Agent) SendHeartbeat() error {
+	err := a.sendHeartbeat()
+	a.connected.Store(err == nil)
+	return err
+}
+
+func (a *Agent) sendHeartbeat() error {
+	resp, err := a.do(http.MethodPost, "/internal/agents/"+a.id+"/heartbeat", nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при отправке heartbeat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return a.Register()
+	default:
+		return fmt.Errorf("heartbeat не принят, код ответа: %d", resp.StatusCode)
+	}
+}
+
+func (a *Agent) heartbeatLoop() {
+	ticker := time.NewTicker(a.heartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.SendHeartbeat(); err != nil {
+			a.logger.Warn("ошибка отправки heartbeat", logging.Err(err))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAgentRegistration(t *testing.T) {
+	t.Setenv("AGENT_ID", "agent-1")
+
+	tests := []struct {
+		name            string
+		heartbeatStatus int
+		wantRegistered  int
+		wantErr         bool
+	}{
+		{"Heartbeat принят", http.StatusOK, 1, false},
+		{"Повторная регистрация после перезапуска оркестратора", http.StatusNotFound, 2, false},
+		{"Ошибка оркестратора", http.StatusInternalServerError, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registered := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(models.AgentIDHeader) != "agent-1" {
+					t.Errorf("❌ %s: запрос без заголовка %s", tt.name, models.AgentIDHeader)
+				}
+
+				switch r.URL.Path {
+				case "/internal/agents":
+					var info struct {
+						ID             string   `json:"id"`
+						ComputingPower int      `json:"computing_power"`
+						Operations     []string `json:"operations"`
+						Version        string   `json:"version"`
+					}
+					json.NewDecoder(r.Body).Decode(&info)
+					if info.ID != "agent-1" || info.ComputingPower != 3 || len(info.Operations) != 4 || info.Version != agent.Version {
+						t.Errorf("❌ %s: неверные данные регистрации: %+v", tt.name, info)
+					}
+					registered++
+					w.WriteHeader(http.StatusCreated)
+				case "/internal/agents/agent-1/heartbeat":
+					w.WriteHeader(tt.heartbeatStatus)
+				}
+			}))
+			defer ts.Close()
+
+			ag := agent.NewAgent(ts.URL, 3)
+			if err := ag.Register(); err != nil {
+				t.Fatalf("❌ %s: не ожидали ошибку регистрации: %v", tt.name, err)
+			}
+
+			err := ag.SendHeartbeat()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("❌ %s: неожиданный результат heartbeat: %v", tt.name, err)
+			}
+			if registered != tt.wantRegistered {
+				t.Fatalf("❌ %s: ожидали %d регистраций, а получили %d", tt.name, tt.wantRegistered, registered)
+			}
+			fmt.Printf("✅ %s: регистраций %d\n", tt.name, registered)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Ошибки выполнения задачи. Повторное выполнение даст тот же результат,
+// поэтому агент сообщает о них оркестратору, а не повторяет задачу.
+var (
+	ErrDivisionByZero   = errors.New("деление на ноль")
+	ErrUnknownOperation = errors.New("неизвестная операция")
+)
+
+// idleInterval — пауза перед следующим запросом, если у оркестратора нет задач.
+const idleInterval = 2 * time.Second
+
+// RetryPolicy — политика повторных запросов к оркестратору.
+type RetryPolicy struct {
+	// MaxAttempts — число попыток подряд отправить пачку результатов. Если
+	// попытки исчерпаны, агент ждёт MaxBackoff и начинает заново: результаты
+	// не теряются. Запрос задач повторяется без ограничения числа попыток.
+	MaxAttempts int
+	// InitialBackoff — пауза после первой неудачной попытки.
+	InitialBackoff time.Duration
+	// MaxBackoff — наибольшая пауза между попытками.
+	MaxBackoff time.Duration
+	// Multiplier — во сколько раз растёт пауза после каждой неудачной попытки.
+	Multiplier float64
+	// Jitter — доля паузы, на которую она случайно сдвигается в обе стороны,
+	// чтобы агенты не повторяли запросы одновременно.
+	Jitter float64
+}
+
+// WithRetryPolicy задаёт политику повторных запросов. По умолчанию политика
+// настраивается переменными AGENT_RETRY_MAX_ATTEMPTS, AGENT_RETRY_INITIAL_MS и AGENT_RETRY_MAX_MS.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(a *Agent) {
+		a.retry = policy
+	}
+}
+
+// retryPolicyFromEnv возвращает политику по умолчанию: 5 попыток, пауза
+// от 500 мс до 30 с, удваивается после каждой попытки и сдвигается на ±20%.
+func retryPolicyFromEnv() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    getEnvInt("AGENT_RETRY_MAX_ATTEMPTS", 5),
+		InitialBackoff: time.Duration(getEnvInt("AGENT_RETRY_INITIAL_MS", 500)) * time.Millisecond,
+		MaxBackoff:     time.Duration(getEnvInt("AGENT_RETRY_MAX_MS", 30_000)) * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// backoff возвращает паузу после attempt неудачных попыток подряд.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := max(p.Multiplier, 1)
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		d = min(d, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// do выполняет op, пока она не завершится успешно, не вернёт ошибку,
+// которую бесполезно повторять, или не будут исчерпаны попытки.
+func (p RetryPolicy) do(op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !retryable(err) || attempt >= max(p.MaxAttempts, 1) {
+			return err
+		}
+		time.Sleep(p.backoff(attempt))
+	}
+}
+
+// statusError — оркестратор ответил неожиданным кодом.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("код ответа: %d", e.code)
+}
+
+// retryable сообщает, может ли повторный запрос завершиться успешно: сетевые
+// ошибки, перегрузка (429) и ошибки сервера (5xx) временные, остальные ответы — нет.
+func retryable(err error) bool {
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code == http.StatusTooManyRequests || status.code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// unauthorized сообщает, что оркестратор не принял токен агента (401 или 403).
+// Результаты при этом верны, поэтому агент не отказывается от них.
+func unauthorized(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) && (status.code == http.StatusUnauthorized || status.code == http.StatusForbidden)
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetry — политика с короткими паузами, чтобы тесты не ждали.
+var fastRetry = agent.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Multiplier: 2}
+
+// retryServer выдаёт задачу один раз и отвечает на отправку результатов кодами из codes по очереди.
+func retryServer(task models.Task, codes []int, results chan<- models.TaskResult) (*httptest.Server, *atomic.Int32) {
+	var fetched atomic.Bool
+	var posts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/internal/agents":
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/internal/task" && r.Method == http.MethodGet:
+			if fetched.Swap(true) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]models.Task{"tasks": {task}})
+		case r.URL.Path == "/internal/task" && r.Method == http.MethodPost:
+			n := int(posts.Add(1))
+			if n <= len(codes) {
+				w.WriteHeader(codes[n-1])
+				return
+			}
+			var batch struct {
+				Results []models.TaskResult `json:"results"`
+			}
+			json.NewDecoder(r.Body).Decode(&batch)
+			json.NewEncoder(w).Encode(map[string]any{"acks": []map[string]any{{"id": task.ID, "status": "ok"}}})
+			for _, res := range batch.Results {
+				results <- res
+			}
+		}
+	}))
+	return ts, &posts
+}
+
+func TestAgentReportsTaskError(t *testing.T) {
+	results := make(chan models.TaskResult, 1)
+	ts, posts := retryServer(models.Task{ID: 7, Arg1: 1, Arg2: 0, Operation: "/"}, nil, results)
+	defer ts.Close()
+
+	agent.NewAgent(ts.URL, 1, agent.WithRetryPolicy(fastRetry)).Start()
+
+	select {
+	case res := <-results:
+		if res.ID != 7 || res.Error != agent.ErrDivisionByZero.Error() {
+			t.Fatalf("❌ ожидали сообщение о делении на ноль, а получили %+v", res)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("❌ агент не сообщил об ошибке задачи")
+	}
+	if n := posts.Load(); n != 1 {
+		t.Fatalf("❌ ожидали одну отправку, а получили %d", n)
+	}
+	fmt.Println("✅ Деление на ноль отправлено оркестратору без повторов")
+}
+
+func TestAgentRetriesSubmit(t *testing.T) {
+	tests := []struct {
+		name      string
+		codes     []int
+		wantPosts int32
+		delivered bool
+	}{
+		{"Временные ошибки", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, 3, true},
+		{"Попытки исчерпаны, результат не потерян", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 4, true},
+		{"Токен агента отклонён, результат не потерян", []int{http.StatusUnauthorized, http.StatusForbidden}, 3, true},
+		{"Ошибка, которую бесполезно повторять", []int{http.StatusBadRequest}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make(chan models.TaskResult, 1)
+			ts, posts := retryServer(models.Task{ID: 1, Arg1: 2, Arg2: 3, Operation: "+"}, tt.codes, results)
+			defer ts.Close()
+
+			agent.NewAgent(ts.URL, 1, agent.WithRetryPolicy(fastRetry)).Start()
+
+			select {
+			case res := <-results:
+				if !tt.delivered || res.Result != 5 {
+					t.Fatalf("❌ неожиданный результат %+v", res)
+				}
+			case <-time.After(500 * time.Millisecond):
+				if tt.delivered {
+					t.Fatal("❌ результат не доставлен")
+				}
+			}
+			if n := posts.Load(); n != tt.wantPosts {
+				t.Fatalf("❌ ожидали %d отправок, а получили %d", tt.wantPosts, n)
+			}
+			fmt.Printf("✅ %s: %d отправок\n", tt.name, tt.wantPosts)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// newHTTPClient создаёт клиент для запросов к оркестратору. Запрос ограничен
+// AGENT_REQUEST_TIMEOUT_MS (по умолчанию 30000), чтобы зависший оркестратор не
+// блокировал агента. ORCHESTRATOR_CA_FILE задаёт CA, которым подписан сертификат
+// оркестратора, а AGENT_TLS_CERT_FILE и AGENT_TLS_KEY_FILE — клиентский сертификат агента для mTLS.
+func newHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(getEnvInt("AGENT_REQUEST_TIMEOUT_MS", 30_000)) * time.Millisecond,
+	}
+
+	caFile := os.Getenv("ORCHESTRATOR_CA_FILE")
+	certFile := os.Getenv("AGENT_TLS_CERT_FILE")
+	keyFile := os.Getenv("AGENT_TLS_KEY_FILE")
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return client, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("в файле %s нет сертификатов", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки сертификата агента: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = config
+	return client, nil
+}
@@ -0,0 +1,86 @@
+package agent_test
+
+import (
+	"Calc_2GO/internal/agent"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAgentTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600)
+
+	tests := []struct {
+		name    string
+		caFile  string
+		wantErr bool
+	}{
+		{"Без CA сертификат оркестратора не доверен", "", true},
+		{"С CA оркестратора", caFile, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ORCHESTRATOR_CA_FILE", tt.caFile)
+
+			err := agent.NewAgent(ts.URL, 1).Register()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("❌ %s: неожиданный результат регистрации: %v", tt.name, err)
+			}
+			fmt.Printf("✅ %s: ошибка %v\n", tt.name, err)
+		})
+	}
+}
+
+func TestAgentTLSConfigError(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	t.Setenv("AGENT_TLS_CERT_FILE", filepath.Join(t.TempDir(), "missing.crt"))
+	t.Setenv("AGENT_TLS_KEY_FILE", filepath.Join(t.TempDir(), "missing.key"))
+
+	ag := agent.NewAgent(ts.URL, 1)
+	if err := ag.Start(); err == nil {
+		t.Fatal("❌ агент запустился с неверным сертификатом")
+	}
+	if err := ag.Register(); err == nil || requests.Load() != 0 {
+		t.Fatalf("❌ агент обратился к оркестратору без сертификата: %v, запросов %d", err, requests.Load())
+	}
+	fmt.Println("✅ Ошибка настройки TLS не даёт агенту запуститься")
+}
+
+func TestAgentRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	t.Setenv("AGENT_REQUEST_TIMEOUT_MS", "100")
+
+	start := time.Now()
+	if err := agent.NewAgent(ts.URL, 1).Register(); err == nil {
+		t.Fatal("❌ ожидали ошибку от зависшего оркестратора")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("❌ запрос к зависшему оркестратору длился %v", elapsed)
+	}
+	fmt.Println("✅ Запрос к зависшему оркестратору прерван по таймауту")
+}
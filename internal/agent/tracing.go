@@ -0,0 +1,46 @@
+package agent
+
+import (
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/tracing"
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "Calc_2GO/internal/agent"
+
+// WithTracerProvider задаёт провайдер трассировок. По умолчанию используется
+// глобальный провайдер OpenTelemetry.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(a *Agent) {
+		a.tracer = tp.Tracer(tracerName)
+	}
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// ExecuteTask выполняет задачу. Спан выполнения становится дочерним для
+// контекста трассировки, который оркестратор передал в задаче.
+func (a *Agent) ExecuteTask(task *models.Task) (float64, error) {
+	ctx := tracing.Extract(context.Background(), task.TraceContext)
+	_, span := a.tracer.Start(ctx, "task.execute", trace.WithAttributes(
+		attribute.Int("expression_id", task.ExpressionID),
+		attribute.Int("task_id", task.ID),
+		attribute.String("operation", task.Operation),
+		attribute.String("agent_id", a.id),
+	))
+	defer span.End()
+
+	result, err := a.execute(task)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	models "Calc_2GO/models"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// loadAgentTokens читает токены агентов из JSON-файла, путь к которому задан
+// в AGENT_TOKENS_FILE. Если файл не задан, возвращается nil и внутренний API
+// доступен без аутентификации. Если файл не удалось прочитать, возвращается
+// пустой набор токенов: аутентификация остаётся включённой.
+func loadAgentTokens(logger *slog.Logger) (map[string]string, error) {
+	path := os.Getenv("AGENT_TOKENS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	tokens := make(map[string]string)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tokens, fmt.Errorf("ошибка при чтении файла токенов агентов %s: %w", path, err)
+	}
+
+	var config struct {
+		Tokens []struct {
+			AgentID string `json:"agent_id"`
+			Token   string `json:"token"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return tokens, fmt.Errorf("ошибка при разборе файла токенов агентов %s: %w", path, err)
+	}
+
+	for _, t := range config.Tokens {
+		if t.AgentID != "" && t.Token != "" {
+			tokens[t.AgentID] = t.Token
+		}
+	}
+	logger.Info("загружены токены агентов", "count", len(tokens))
+	return tokens, nil
+}
+
+// authenticateAgent проверяет, что агент из заголовка X-Agent-ID предъявил свой токен.
+// Если агент подключился с клиентским сертификатом (mTLS), его Common Name
+// должен совпадать с X-Agent-ID.
+func (o *Orchestrator) authenticateAgent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+			if cn != r.Header.Get(models.AgentIDHeader) {
+				http.Error(w, "❌ Сертификат выдан другому агенту", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if o.agentTokens == nil {
+			next(w, r)
+			return
+		}
+
+		expected, known := o.agentTokens[r.Header.Get(models.AgentIDHeader)]
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !known || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calc-internal"`)
+			http.Error(w, "❌ Агент не прошёл аутентификацию", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
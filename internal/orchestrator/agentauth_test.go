@@ -0,0 +1,123 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInternalAuthentication(t *testing.T) {
+	tokensFile := filepath.Join(t.TempDir(), "agents.json")
+	os.WriteFile(tokensFile, []byte(`{"tokens": [
+		{"agent_id": "agent-1", "token": "token-1"},
+		{"agent_id": "agent-2", "token": "token-2"}
+	]}`), 0o600)
+	t.Setenv("AGENT_TOKENS_FILE", tokensFile)
+
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.InternalHandler())
+	defer srv.Close()
+
+	o.AddExpression("2+2")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		agent      string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{"Без токена", http.MethodGet, "/internal/task", "agent-1", "", "", http.StatusUnauthorized},
+		{"Чужой токен", http.MethodGet, "/internal/task", "agent-1", "token-2", "", http.StatusUnauthorized},
+		{"Неизвестный агент", http.MethodGet, "/internal/task", "agent-3", "token-1", "", http.StatusUnauthorized},
+		{"Регистрация от чужого имени", http.MethodPost, "/internal/agents", "agent-2", "token-2", `{"id": "agent-1"}`, http.StatusForbidden},
+		{"Получение задачи", http.MethodGet, "/internal/task", "agent-1", "token-1", "", http.StatusOK},
+		{"Результат от другого агента", http.MethodPost, "/internal/task", "agent-2", "token-2", `{"id": 1, "result": 4}`, http.StatusForbidden},
+		{"Результат от держателя задачи", http.MethodPost, "/internal/task", "agent-1", "token-1", `{"id": 1, "result": 4}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			req.Header.Set(models.AgentIDHeader, tt.agent)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("❌ %s: ошибка запроса: %v", tt.name, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("❌ %s: ожидали код %d, а получили %d", tt.name, tt.wantStatus, resp.StatusCode)
+			}
+			fmt.Printf("✅ %s: код %d\n", tt.name, resp.StatusCode)
+		})
+	}
+
+	if expr, _ := o.GetExpression(1); expr.Status != orchestrator.StatusDone || expr.Result != 4 {
+		t.Fatalf("❌ ожидали завершённое выражение, а получили %+v", expr)
+	}
+}
+
+func TestAgentTokensConfigError(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"tokens": [`), 0o600)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Файл не найден", filepath.Join(dir, "missing.json")},
+		{"Неверный JSON", invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AGENT_TOKENS_FILE", tt.path)
+			t.Setenv("PUBLIC_ADDR", "127.0.0.1:0")
+			t.Setenv("INTERNAL_ADDR", "127.0.0.1:0")
+
+			o := orchestrator.NewOrchestrator(orchestrator.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+			srv := httptest.NewServer(o.InternalHandler())
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/internal/task", nil)
+			req.Header.Set(models.AgentIDHeader, "agent-1")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("❌ %s: ошибка запроса: %v", tt.name, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("❌ %s: ожидали код 401, а получили %d", tt.name, resp.StatusCode)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- o.StartServer() }()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Fatalf("❌ %s: StartServer не вернул ошибку", tt.name)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("❌ %s: сервер запустился с ошибкой в файле токенов", tt.name)
+			}
+			fmt.Printf("✅ %s: запуск отклонён, агенты без токена не допускаются\n", tt.name)
+		})
+	}
+}
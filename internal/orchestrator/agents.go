@@ -0,0 +1,256 @@
+package orchestrator
+
+import (
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Статусы агента.
+const (
+	AgentAlive = "alive"
+	AgentDead  = "dead"
+)
+
+// AgentInfo — зарегистрированный агент.
+type AgentInfo struct {
+	ID             string    `json:"id"`
+	Hostname       string    `json:"hostname"`
+	ComputingPower int       `json:"computing_power"`
+	Operations     []string  `json:"operations"`
+	Version        string    `json:"version"`
+	Status         string    `json:"status"`
+	RegisteredAt   time.Time `json:"registered_at"`
+	LastHeartbeat  time.Time `json:"last_heartbeat"`
+}
+
+// RegisterAgent добавляет агента в реестр или обновляет сведения о нём.
+func (o *Orchestrator) RegisterAgent(info AgentInfo) error {
+	if info.ID == "" {
+		return fmt.Errorf("не указан id агента")
+	}
+	return o.propose(command{Type: cmdRegisterAgent, At: time.Now(), AgentInfo: &info}).Err
+}
+
+// registerAgent записывает агента в реестр. Повторная регистрация означает,
+// что агент перезапустился и потерял выданные ему задачи: они возвращаются
+// в очередь. Вызывается под o.mu.
+func (o *Orchestrator) registerAgent(info AgentInfo, now time.Time) {
+	if _, exists := o.agents[info.ID]; exists {
+		if tasks := o.releaseAgentTasks(info.ID, now); len(tasks) > 0 {
+			o.requeue(tasks)
+			o.logger.Warn("агент перезапущен, его задачи возвращены в очередь", logging.AgentID(info.ID), "requeued", len(tasks))
+		}
+	}
+
+	info.Status = AgentAlive
+	info.RegisteredAt = now
+	info.LastHeartbeat = now
+	o.agents[info.ID] = &info
+
+	o.logger.Info("агент зарегистрирован", logging.AgentID(info.ID), "hostname", info.Hostname, "computing_power", info.ComputingPower, "operations", info.Operations, "version", info.Version)
+}
+
+// Heartbeat отмечает, что агент жив. Возвращает false, если агент не зарегистрирован.
+func (o *Orchestrator) Heartbeat(id string) bool {
+	res := o.propose(command{Type: cmdHeartbeat, At: time.Now(), Agent: id})
+	if res.Err != nil {
+		o.logger.Warn("ошибка при записи heartbeat", logging.AgentID(id), logging.Err(res.Err))
+	}
+	return res.OK
+}
+
+// heartbeat обновляет время последнего heartbeat агента. Вызывается под o.mu.
+func (o *Orchestrator) heartbeat(id string, now time.Time) bool {
+	agent, exists := o.agents[id]
+	if !exists {
+		return false
+	}
+
+	if agent.Status == AgentDead {
+		o.logger.Info("агент снова на связи", logging.AgentID(id))
+	}
+	agent.Status = AgentAlive
+	agent.LastHeartbeat = now
+	return true
+}
+
+// GetAgents возвращает реестр агентов, упорядоченный по ID.
+func (o *Orchestrator) GetAgents() []AgentInfo {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	agents := make([]AgentInfo, 0, len(o.agents))
+	for _, agent := range o.agents {
+		agents = append(agents, *agent)
+	}
+	slices.SortFunc(agents, func(a, b AgentInfo) int { return strings.Compare(a.ID, b.ID) })
+	return agents
+}
+
+// ReapAgents помечает мёртвыми агентов, от которых не было heartbeat дольше
+// таймаута, и возвращает в очередь выданные им задачи, а также задачи,
+// аренда которых истекла.
+func (o *Orchestrator) ReapAgents(now time.Time) {
+	if !o.hasExpired(now) {
+		return
+	}
+	if res := o.propose(command{Type: cmdReapAgents, At: now}); res.Err != nil {
+		o.logger.Warn("ошибка при проверке агентов", logging.Err(res.Err))
+	}
+}
+
+// hasExpired сообщает, есть ли живые агенты без heartbeat дольше таймаута
+// или задачи с истёкшей арендой.
+func (o *Orchestrator) hasExpired(now time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, agent := range o.agents {
+		if agent.Status == AgentAlive && now.Sub(agent.LastHeartbeat) > o.heartbeatTimeout {
+			return true
+		}
+	}
+	for _, info := range o.taskInfo {
+		if info.leaseExpired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// reapAgents помечает мёртвыми агентов с истёкшим heartbeat и возвращает
+// в очередь их задачи и задачи с истёкшей арендой. Вызывается под o.mu.
+func (o *Orchestrator) reapAgents(now time.Time) {
+	var requeued []models.Task
+	for _, agent := range o.agents {
+		if agent.Status == AgentDead || now.Sub(agent.LastHeartbeat) <= o.heartbeatTimeout {
+			continue
+		}
+
+		agent.Status = AgentDead
+		tasks := o.releaseAgentTasks(agent.ID, now)
+		requeued = append(requeued, tasks...)
+		o.logger.Warn("агент не отвечает, его задачи возвращены в очередь", logging.AgentID(agent.ID), "requeued", len(tasks))
+	}
+
+	// Агент жив, но не вернул результат до конца аренды, например потерял его.
+	for _, info := range o.taskInfo {
+		if info.leaseExpired(now) {
+			o.logger.Warn("аренда задачи истекла, задача возвращена в очередь", logging.TaskID(info.ID), logging.AgentID(info.Agent))
+			requeued = append(requeued, o.releaseTask(info, now))
+		}
+	}
+
+	o.requeue(requeued)
+	o.failUnroutable(now)
+}
+
+// requeue ставит освобождённые задачи в начало очереди. Вызывается под o.mu.
+func (o *Orchestrator) requeue(tasks []models.Task) {
+	// Агенты и задачи перебираются в случайном порядке, поэтому задачи упорядочиваются
+	// по ID: очередь должна совпадать на всех репликах и при повторе журнала.
+	slices.SortFunc(tasks, func(a, b models.Task) int { return a.ID - b.ID })
+	o.tasks = append(tasks, o.tasks...)
+	if !o.quiet {
+		o.metrics.leaseExpirations.Add(float64(len(tasks)))
+	}
+}
+
+// releaseAgentTasks снимает с агента выданные ему задачи и возвращает их
+// для постановки в очередь. Вызывается под o.mu.
+func (o *Orchestrator) releaseAgentTasks(agentID string, now time.Time) []models.Task {
+	var released []models.Task
+	for _, info := range o.taskInfo {
+		if info.Status == TaskInProgress && info.Agent == agentID {
+			released = append(released, o.releaseTask(info, now))
+		}
+	}
+	return released
+}
+
+// releaseTask снимает задачу с агента и возвращает её для постановки в очередь.
+// Вызывается под o.mu.
+func (o *Orchestrator) releaseTask(info *TaskInfo, now time.Time) models.Task {
+	info.Status = TaskQueued
+	info.QueuedAt = now
+	info.StartedAt = nil
+	info.LeaseDeadline = nil
+	endSpan(info.span, errLeaseExpired)
+	o.startTaskSpan(o.expressions[info.ExpressionID], info, "task.queue")
+	return info.task
+}
+
+func (o *Orchestrator) runAgentReaper() {
+	ticker := time.NewTicker(o.heartbeatTimeout / 3)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		// Агентов отслеживает только лидер: у остальных реплик нет их задач.
+		if o.IsLeader() {
+			o.ReapAgents(now)
+		}
+	}
+}
+
+// HandleAgents обрабатывает регистрацию агента (POST /internal/agents)
+// и heartbeat (POST /internal/agents/{id}/heartbeat).
+func (o *Orchestrator) HandleAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "❌ Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Агент, который представился, может говорить только от своего имени.
+	caller := r.Header.Get(models.AgentIDHeader)
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/internal/agents"), "/")
+	if id, ok := strings.CutSuffix(path, "/heartbeat"); ok {
+		if caller != "" && caller != id {
+			http.Error(w, "❌ Heartbeat от имени другого агента", http.StatusForbidden)
+			return
+		}
+		if !o.Heartbeat(id) {
+			http.Error(w, "❌ Агент не зарегистрирован", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if path != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var info AgentInfo
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		http.Error(w, fmt.Sprintf("❌ Ошибка при чтении данных: %v", err), http.StatusBadRequest)
+		return
+	}
+	if caller != "" && caller != info.ID {
+		http.Error(w, "❌ Регистрация от имени другого агента", http.StatusForbidden)
+		return
+	}
+
+	err := o.RegisterAgent(info)
+	if stateUnavailable(err) {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (o *Orchestrator) HandleGetAgents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]AgentInfo{"agents": o.GetAgents()})
+}
@@ -0,0 +1,162 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentRegistry(t *testing.T) {
+	t.Setenv("AGENT_HEARTBEAT_TIMEOUT_MS", "1000")
+	o := orchestrator.NewOrchestrator()
+
+	rec := httptest.NewRecorder()
+	o.HandleAgents(rec, httptest.NewRequest(http.MethodPost, "/internal/agents",
+		strings.NewReader(`{"id": "test-agent", "hostname": "host", "computing_power": 2, "operations": ["+", "-", "*", "/"], "version": "1.1.0"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("❌ ожидали код 201 при регистрации, а получили %d", rec.Code)
+	}
+
+	id, _ := o.AddExpression("2+2")
+	task := fetchTask(t, o)
+
+	rec = httptest.NewRecorder()
+	o.HandleAgents(rec, httptest.NewRequest(http.MethodPost, "/internal/agents/test-agent/heartbeat", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("❌ ожидали код 200 на heartbeat, а получили %d", rec.Code)
+	}
+
+	tests := []struct {
+		name       string
+		after      time.Duration
+		wantStatus string
+		wantTask   string
+		wantQueued bool
+	}{
+		{"Агент жив", 500 * time.Millisecond, orchestrator.AgentAlive, orchestrator.TaskInProgress, false},
+		{"Пропущены heartbeat", 2 * time.Second, orchestrator.AgentDead, orchestrator.TaskQueued, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o.ReapAgents(time.Now().Add(tt.after))
+
+			rec := httptest.NewRecorder()
+			o.HandleGetAgents(rec, httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+			var response struct {
+				Agents []orchestrator.AgentInfo `json:"agents"`
+			}
+			json.NewDecoder(rec.Body).Decode(&response)
+
+			if len(response.Agents) != 1 || response.Agents[0].Status != tt.wantStatus || response.Agents[0].ComputingPower != 2 {
+				t.Fatalf("❌ %s: неожиданный реестр агентов: %+v", tt.name, response.Agents)
+			}
+
+			tasks, _ := o.GetExpressionTasks(id)
+			if tasks[0].Status != tt.wantTask {
+				t.Fatalf("❌ %s: ожидали статус задачи '%s', а получили '%s'", tt.name, tt.wantTask, tasks[0].Status)
+			}
+
+			requeued, exists := o.GetNextTask()
+			if exists != tt.wantQueued || (exists && requeued.ID != task.ID) {
+				t.Fatalf("❌ %s: неожиданное состояние очереди: %+v", tt.name, requeued)
+			}
+			fmt.Printf("✅ %s: агент %s, задача %s\n", tt.name, tt.wantStatus, tt.wantTask)
+		})
+	}
+}
+
+func TestReapAgentsRequeueOrder(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	agents := []string{"agent-a", "agent-b", "agent-c", "agent-d", "agent-e"}
+	for _, id := range agents {
+		o.RegisterAgent(orchestrator.AgentInfo{ID: id, Operations: []string{"+"}})
+	}
+	for i := 1; i <= 10; i++ {
+		o.AddExpression(fmt.Sprintf("%d+%d", i, i))
+	}
+
+	// Задачи раздаются агентам по очереди, чтобы у каждого оказались несмежные ID.
+	for i := 0; i < 10; i++ {
+		if tasks := o.NextTasks(agents[i%len(agents)], 1); len(tasks) != 1 {
+			t.Fatalf("❌ ожидали задачу, а получили %+v", tasks)
+		}
+	}
+
+	o.ReapAgents(time.Now().Add(time.Hour))
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-f", Operations: []string{"+"}})
+
+	tasks := o.NextTasks("agent-f", 10)
+	if len(tasks) != 10 {
+		t.Fatalf("❌ ожидали 10 задач, а получили %d", len(tasks))
+	}
+	for i, task := range tasks {
+		if task.ID != i+1 {
+			t.Fatalf("❌ ожидали задачи в порядке ID, а получили %+v", tasks)
+		}
+	}
+	fmt.Println("✅ Задачи мёртвых агентов возвращены в очередь в порядке ID")
+}
+
+func TestAgentReregisterRequeuesTasks(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-1", Operations: []string{"+"}})
+	id, _ := o.AddExpression("1+1")
+
+	leased := o.NextTasks("agent-1", 1)
+	if len(leased) != 1 {
+		t.Fatalf("❌ ожидали задачу, а получили %+v", leased)
+	}
+
+	// Агент перезапустился с тем же AGENT_ID раньше, чем истёк таймаут heartbeat.
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-1", Operations: []string{"+"}})
+
+	tasks := o.NextTasks("agent-1", 1)
+	if len(tasks) != 1 || tasks[0].ID != leased[0].ID {
+		t.Fatalf("❌ ожидали повторную выдачу задачи %d, а получили %+v", leased[0].ID, tasks)
+	}
+	o.RecordResults("agent-1", []models.TaskResult{{ID: tasks[0].ID, Result: 2}})
+	if expr, _ := o.GetExpression(id); expr.Status != orchestrator.StatusDone {
+		t.Fatalf("❌ ожидали статус %s, а получили %s", orchestrator.StatusDone, expr.Status)
+	}
+	fmt.Println("✅ Задачи перезапущенного агента возвращены в очередь")
+}
+
+func TestTaskLeaseDeadline(t *testing.T) {
+	t.Setenv("TASK_LEASE_TIMEOUT_MS", "1000")
+	o := orchestrator.NewOrchestrator()
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-1", Operations: []string{"+"}})
+	o.AddExpression("1+1")
+
+	leased := o.NextTasks("agent-1", 1)
+	if len(leased) != 1 {
+		t.Fatalf("❌ ожидали задачу, а получили %+v", leased)
+	}
+	info, _ := o.GetExpressionTasks(leased[0].ExpressionID)
+	if info[0].LeaseDeadline == nil {
+		t.Fatalf("❌ у выданной задачи нет срока аренды: %+v", info[0])
+	}
+
+	// Агент жив и шлёт heartbeat, но результат так и не вернул.
+	now := time.Now()
+	o.ReapAgents(now.Add(500 * time.Millisecond))
+	if tasks := o.NextTasks("agent-2", 1); len(tasks) != 0 {
+		t.Fatalf("❌ аренда ещё не истекла, а задача выдана повторно: %+v", tasks)
+	}
+
+	o.ReapAgents(now.Add(2 * time.Second))
+	tasks := o.NextTasks("agent-2", 1)
+	if len(tasks) != 1 || tasks[0].ID != leased[0].ID {
+		t.Fatalf("❌ ожидали повторную выдачу задачи %d, а получили %+v", leased[0].ID, tasks)
+	}
+	if agents := o.GetAgents(); agents[0].Status != orchestrator.AgentAlive {
+		t.Fatalf("❌ агент не должен считаться мёртвым: %+v", agents[0])
+	}
+	fmt.Println("✅ Задача с истёкшей арендой возвращена в очередь")
+}
@@ -0,0 +1,350 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyHeader — альтернатива заголовку Authorization: Bearer для передачи API-ключа.
+const APIKeyHeader = "X-API-Key"
+
+// Principal — владелец API-ключа.
+type Principal struct {
+	Name  string `json:"principal"`
+	Admin bool   `json:"admin"`
+}
+
+// APIKey — сведения о ключе без самого ключа.
+type APIKey struct {
+	ID string `json:"id"`
+	Principal
+}
+
+// apiKeyConfig — запись файла ключей API_KEYS_FILE.
+type apiKeyConfig struct {
+	Key       string `json:"key"`
+	Principal string `json:"principal"`
+	Admin     bool   `json:"admin"`
+}
+
+// keyStore хранит хеши ключей, а не сами ключи.
+type keyStore struct {
+	mu sync.RWMutex
+	// keys — ключи из API_KEYS_FILE и ADMIN_API_KEY: sha256(key) -> владелец.
+	keys map[string]Principal
+	// issued — ключи, выпущенные через API, revoked — отозванные ключи из keys.
+	// Они меняются только командами и сохраняются в снимке состояния.
+	issued  map[string]Principal
+	revoked map[string]bool
+	// configured — аутентификация включена: задан API_KEYS_FILE или ADMIN_API_KEY.
+	// Отзыв всех ключей её не выключает.
+	configured bool
+}
+
+// apiKeyState — выпущенный через API ключ в снимке состояния.
+type apiKeyState struct {
+	Hash string `json:"hash"`
+	Principal
+}
+
+type principalKey struct{}
+
+// newKeyStore загружает ключи из JSON-файла, путь к которому задан в API_KEYS_FILE,
+// и ключ администратора из ADMIN_API_KEY. Если ни то ни другое не задано,
+// аутентификация выключена, а управление ключами недоступно. Если файл не удалось
+// прочитать, аутентификация остаётся включённой без ключей.
+func newKeyStore(logger *slog.Logger) (*keyStore, error) {
+	s := &keyStore{keys: make(map[string]Principal), issued: make(map[string]Principal), revoked: make(map[string]bool)}
+
+	if key := os.Getenv("ADMIN_API_KEY"); key != "" {
+		s.keys[hashKey(key)] = Principal{Name: "admin", Admin: true}
+		s.configured = true
+	}
+
+	path := os.Getenv("API_KEYS_FILE")
+	if path == "" {
+		return s, nil
+	}
+	s.configured = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("ошибка при чтении файла ключей %s: %w", path, err)
+	}
+
+	var config struct {
+		Keys []apiKeyConfig `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return s, fmt.Errorf("ошибка при разборе файла ключей %s: %w", path, err)
+	}
+
+	for _, k := range config.Keys {
+		if k.Key == "" || k.Principal == "" {
+			continue
+		}
+		s.keys[hashKey(k.Key)] = Principal{Name: k.Principal, Admin: k.Admin}
+	}
+	logger.Info("загружены API-ключи", "count", len(s.keys))
+	return s, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// keyID — короткий идентификатор ключа для списка и удаления.
+func keyID(hash string) string {
+	return hash[:12]
+}
+
+func (s *keyStore) enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.configured
+}
+
+func (s *keyStore) lookup(key string) (Principal, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hash := hashKey(key)
+	if p, ok := s.issued[hash]; ok {
+		return p, true
+	}
+	p, ok := s.keys[hash]
+	return p, ok && !s.revoked[hash]
+}
+
+// add добавляет выпущенный ключ.
+func (s *keyStore) add(hash string, p Principal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issued[hash] = p
+}
+
+// revoke отзывает ключ по его идентификатору.
+func (s *keyStore) revoke(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash := range s.issued {
+		if keyID(hash) == id {
+			delete(s.issued, hash)
+			return true
+		}
+	}
+	for hash := range s.keys {
+		if keyID(hash) == id && !s.revoked[hash] {
+			s.revoked[hash] = true
+			return true
+		}
+	}
+	return false
+}
+
+// list возвращает действующие ключи, упорядоченные по владельцу.
+func (s *keyStore) list() []APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(s.keys)+len(s.issued))
+	for hash, p := range s.keys {
+		if !s.revoked[hash] {
+			keys = append(keys, APIKey{ID: keyID(hash), Principal: p})
+		}
+	}
+	for hash, p := range s.issued {
+		keys = append(keys, APIKey{ID: keyID(hash), Principal: p})
+	}
+	slices.SortFunc(keys, func(a, b APIKey) int {
+		return strings.Compare(a.Name+a.ID, b.Name+b.ID)
+	})
+	return keys
+}
+
+// state копирует выпущенные и отозванные ключи для снимка состояния.
+func (s *keyStore) state() ([]apiKeyState, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	issued := make([]apiKeyState, 0, len(s.issued))
+	for hash, p := range s.issued {
+		issued = append(issued, apiKeyState{Hash: hash, Principal: p})
+	}
+	slices.SortFunc(issued, func(a, b apiKeyState) int { return strings.Compare(a.Hash, b.Hash) })
+
+	revoked := make([]string, 0, len(s.revoked))
+	for hash := range s.revoked {
+		revoked = append(revoked, hash)
+	}
+	slices.Sort(revoked)
+	return issued, revoked
+}
+
+// restore заменяет выпущенные и отозванные ключи ключами из снимка.
+func (s *keyStore) restore(issued []apiKeyState, revoked []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.issued = make(map[string]Principal, len(issued))
+	for _, k := range issued {
+		s.issued[k.Hash] = k.Principal
+	}
+	s.revoked = make(map[string]bool, len(revoked))
+	for _, hash := range revoked {
+		s.revoked[hash] = true
+	}
+}
+
+// CreateAPIKey выпускает новый ключ и возвращает его. Ключ показывается только один раз.
+// Выпуск ключа не включает аутентификацию, если она выключена. В журнал команд
+// попадает только хеш ключа.
+func (o *Orchestrator) CreateAPIKey(p Principal) (string, APIKey, error) {
+	if p.Name == "" {
+		return "", APIKey{}, fmt.Errorf("не указан principal")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", APIKey{}, err
+	}
+	key := hex.EncodeToString(raw)
+	hash := hashKey(key)
+
+	if res := o.propose(command{Type: cmdCreateAPIKey, At: time.Now(), KeyHash: hash, Principal: &p}); res.Err != nil {
+		return "", APIKey{}, res.Err
+	}
+	return key, APIKey{ID: keyID(hash), Principal: p}, nil
+}
+
+// ListAPIKeys возвращает действующие ключи, упорядоченные по владельцу.
+func (o *Orchestrator) ListAPIKeys() []APIKey {
+	return o.keys.list()
+}
+
+// RevokeAPIKey удаляет ключ по его идентификатору. false — ключ не найден.
+func (o *Orchestrator) RevokeAPIKey(id string) (bool, error) {
+	res := o.propose(command{Type: cmdRevokeAPIKey, At: time.Now(), KeyID: id})
+	return res.OK, res.Err
+}
+
+// authenticate проверяет API-ключ и кладёт его владельца в контекст запроса.
+func (o *Orchestrator) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !o.keys.enabled() {
+			next(w, r)
+			return
+		}
+
+		key := r.Header.Get(APIKeyHeader)
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = bearer
+		}
+
+		p, ok := o.keys.lookup(key)
+		if key == "" || !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calc"`)
+			http.Error(w, "❌ Требуется действительный API-ключ", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// requireAdmin пропускает только запросы администраторов. Пока аутентификация
+// выключена, администраторов нет и административный API недоступен.
+func (o *Orchestrator) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	admin := o.authenticate(func(w http.ResponseWriter, r *http.Request) {
+		if p, _ := principalFrom(r); !p.Admin {
+			http.Error(w, "❌ Недостаточно прав", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !o.keys.enabled() {
+			http.Error(w, "❌ Административный API выключен: не заданы API_KEYS_FILE и ADMIN_API_KEY", http.StatusForbidden)
+			return
+		}
+		admin(w, r)
+	}
+}
+
+// principalFrom возвращает владельца ключа запроса. false — аутентификация выключена.
+func principalFrom(r *http.Request) (Principal, bool) {
+	p, ok := r.Context().Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// ownerFilter возвращает владельца, которым ограничена выборка, или "" — без ограничений.
+func ownerFilter(r *http.Request) string {
+	if p, ok := principalFrom(r); ok && !p.Admin {
+		return p.Name
+	}
+	return ""
+}
+
+func canAccess(r *http.Request, expr *Expression) bool {
+	owner := ownerFilter(r)
+	return owner == "" || expr.Owner == owner
+}
+
+// HandleAdminKeys управляет API-ключами:
+// GET /api/v1/admin/keys, POST /api/v1/admin/keys, DELETE /api/v1/admin/keys/{id}.
+func (o *Orchestrator) HandleAdminKeys(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/keys"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]APIKey{"keys": o.ListAPIKeys()})
+	case r.Method == http.MethodPost && id == "":
+		var p Principal
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, fmt.Sprintf("❌ Ошибка при чтении данных: %v", err), http.StatusBadRequest)
+			return
+		}
+		key, info, err := o.CreateAPIKey(p)
+		if stateUnavailable(err) {
+			http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(struct {
+			Key string `json:"key"`
+			APIKey
+		}{key, info})
+	case r.Method == http.MethodDelete && id != "":
+		revoked, err := o.RevokeAPIKey(id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		if !revoked {
+			http.Error(w, "❌ Ключ не найден", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "❌ Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
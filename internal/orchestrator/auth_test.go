@@ -0,0 +1,262 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthentication(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(keysFile, []byte(`{"keys": [
+		{"key": "alice-key", "principal": "alice"},
+		{"key": "bob-key", "principal": "bob"},
+		{"key": "admin-key", "principal": "admin", "admin": true}
+	]}`), 0o600)
+	t.Setenv("API_KEYS_FILE", keysFile)
+
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	defer srv.Close()
+
+	aliceID := submit(t, srv.URL, "alice-key", "1+1")
+	submit(t, srv.URL, "bob-key", "2+2")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		key        string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"Без ключа", http.MethodGet, "/api/v1/expressions", "", "", http.StatusUnauthorized, ""},
+		{"Неверный ключ", http.MethodGet, "/api/v1/expressions", "wrong", "", http.StatusUnauthorized, ""},
+		{"Свои выражения", http.MethodGet, "/api/v1/expressions", "alice-key", "", http.StatusOK, `"owner":"alice"`},
+		{"Чужое выражение", http.MethodGet, "/api/v1/expressions/" + aliceID, "bob-key", "", http.StatusNotFound, ""},
+		{"Своё выражение", http.MethodGet, "/api/v1/expressions/" + aliceID, "alice-key", "", http.StatusOK, `"owner":"alice"`},
+		{"Администратор видит всё", http.MethodGet, "/api/v1/expressions", "admin-key", "", http.StatusOK, `"owner":"bob"`},
+		{"Управление ключами без прав", http.MethodGet, "/api/v1/admin/keys", "alice-key", "", http.StatusForbidden, ""},
+		{"Выпуск ключа", http.MethodPost, "/api/v1/admin/keys", "admin-key", `{"principal": "carol"}`, http.StatusCreated, `"principal":"carol"`},
+		{"Внутренний API недоступен снаружи", http.MethodGet, "/internal/task", "admin-key", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := call(t, tt.method, srv.URL+tt.path, tt.key, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("❌ %s: ожидали код %d, а получили %d: %s", tt.name, tt.wantStatus, status, body)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Fatalf("❌ %s: ожидали в ответе %s, а получили %s", tt.name, tt.wantBody, body)
+			}
+			if tt.name == "Свои выражения" && strings.Contains(body, `"owner":"bob"`) {
+				t.Fatalf("❌ %s: в списке есть чужие выражения: %s", tt.name, body)
+			}
+			fmt.Printf("✅ %s: код %d\n", tt.name, status)
+		})
+	}
+
+	t.Run("Выпущенный ключ работает", func(t *testing.T) {
+		_, body := call(t, http.MethodPost, srv.URL+"/api/v1/admin/keys", "admin-key", `{"principal": "dave"}`)
+		var created struct {
+			Key string `json:"key"`
+		}
+		json.Unmarshal([]byte(body), &created)
+
+		if status, _ := call(t, http.MethodGet, srv.URL+"/api/v1/expressions", created.Key, ""); status != http.StatusOK {
+			t.Fatalf("❌ ожидали код 200 с новым ключом, а получили %d", status)
+		}
+		fmt.Println("✅ новый ключ принят")
+	})
+}
+
+func submit(t *testing.T, baseURL, key, expr string) string {
+	t.Helper()
+	status, body := call(t, http.MethodPost, baseURL+"/api/v1/calculate", key, fmt.Sprintf(`{"expression": "%s"}`, expr))
+	if status != http.StatusCreated {
+		t.Fatalf("❌ не удалось отправить выражение, код ответа: %d", status)
+	}
+	var response struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal([]byte(body), &response)
+	return response.ID
+}
+
+func call(t *testing.T, method, url, key, body string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("❌ ошибка запроса: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestAuthStaysEnabled(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(keysFile, []byte(`{"keys": [{"key": "admin-key", "principal": "admin", "admin": true}]}`), 0o600)
+	t.Setenv("API_KEYS_FILE", keysFile)
+
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	defer srv.Close()
+
+	// Администратор отзывает последний ключ — свой.
+	keys := o.ListAPIKeys()
+	if status, body := call(t, http.MethodDelete, srv.URL+"/api/v1/admin/keys/"+keys[0].ID, "admin-key", ""); status >= 300 {
+		t.Fatalf("❌ не удалось отозвать ключ: %d %s", status, body)
+	}
+	if status, _ := call(t, http.MethodGet, srv.URL+"/api/v1/expressions", "", ""); status != http.StatusUnauthorized {
+		t.Fatalf("❌ после отзыва всех ключей ожидали код 401, а получили %d", status)
+	}
+	fmt.Println("✅ Отзыв последнего ключа не выключает аутентификацию")
+}
+
+func TestAuthConfigError(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"keys": [`), 0o600)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Файл не найден", filepath.Join(dir, "missing.json")},
+		{"Неверный JSON", invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("API_KEYS_FILE", tt.path)
+			t.Setenv("PUBLIC_ADDR", "127.0.0.1:0")
+			t.Setenv("INTERNAL_ADDR", "127.0.0.1:0")
+
+			o := orchestrator.NewOrchestrator(orchestrator.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+			srv := httptest.NewServer(o.PublicHandler())
+			defer srv.Close()
+
+			if status, _ := call(t, http.MethodGet, srv.URL+"/api/v1/expressions", "", ""); status != http.StatusUnauthorized {
+				t.Fatalf("❌ %s: ожидали код 401, а получили %d", tt.name, status)
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- o.StartServer() }()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Fatalf("❌ %s: StartServer не вернул ошибку", tt.name)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("❌ %s: сервер запустился с ошибкой в файле ключей", tt.name)
+			}
+			fmt.Printf("✅ %s: запуск отклонён, запросы без ключа запрещены\n", tt.name)
+		})
+	}
+}
+
+func TestAdminAPIDisabled(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	defer srv.Close()
+
+	for _, path := range []string{"/api/v1/admin/keys", "/api/v1/callbacks/dead-letters"} {
+		if status, body := call(t, http.MethodGet, srv.URL+path, "", ""); status != http.StatusForbidden {
+			t.Fatalf("❌ %s без настроенной аутентификации: ожидали код 403, а получили %d: %s", path, status, body)
+		}
+	}
+	if status, body := call(t, http.MethodPost, srv.URL+"/api/v1/admin/keys", "", `{"principal": "mallory", "admin": true}`); status != http.StatusForbidden {
+		t.Fatalf("❌ анонимный выпуск ключа администратора: ожидали код 403, а получили %d: %s", status, body)
+	}
+	submit(t, srv.URL, "", "1+1")
+	fmt.Println("✅ Без настроенной аутентификации административный API недоступен")
+}
+
+func TestAdminAPIKeyBootstrap(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "bootstrap-key")
+
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	defer srv.Close()
+
+	if status, _ := call(t, http.MethodGet, srv.URL+"/api/v1/expressions", "", ""); status != http.StatusUnauthorized {
+		t.Fatalf("❌ без ключа ожидали код 401, а получили %d", status)
+	}
+
+	status, body := call(t, http.MethodPost, srv.URL+"/api/v1/admin/keys", "bootstrap-key", `{"principal": "alice"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("❌ ожидали код 201, а получили %d: %s", status, body)
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	json.Unmarshal([]byte(body), &created)
+	submit(t, srv.URL, created.Key, "2+2")
+	fmt.Println("✅ Ключ, выпущенный с ADMIN_API_KEY, принят")
+}
+
+func TestAPIKeysRecovery(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(keysFile, []byte(`{"keys": [
+		{"key": "alice-key", "principal": "alice"},
+		{"key": "admin-key", "principal": "admin", "admin": true}
+	]}`), 0o600)
+	t.Setenv("API_KEYS_FILE", keysFile)
+
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir, SnapshotEvery: 3})
+	srv := httptest.NewServer(o.PublicHandler())
+
+	_, body := call(t, http.MethodPost, srv.URL+"/api/v1/admin/keys", "admin-key", `{"principal": "bob"}`)
+	var created struct {
+		Key string `json:"key"`
+		ID  string `json:"id"`
+	}
+	json.Unmarshal([]byte(body), &created)
+	for _, k := range o.ListAPIKeys() {
+		if k.Name == "alice" {
+			call(t, http.MethodDelete, srv.URL+"/api/v1/admin/keys/"+k.ID, "admin-key", "")
+		}
+	}
+	srv.Close()
+
+	check := func(o *orchestrator.Orchestrator, stage string) {
+		srv := httptest.NewServer(o.PublicHandler())
+		defer srv.Close()
+		if status, _ := call(t, http.MethodGet, srv.URL+"/api/v1/expressions", created.Key, ""); status != http.StatusOK {
+			t.Fatalf("❌ %s: выпущенный ключ не принят, код %d", stage, status)
+		}
+		if status, _ := call(t, http.MethodGet, srv.URL+"/api/v1/expressions", "alice-key", ""); status != http.StatusUnauthorized {
+			t.Fatalf("❌ %s: отозванный ключ принят, код %d", stage, status)
+		}
+		fmt.Printf("✅ %s: выпущенный ключ действует, отозванный — нет\n", stage)
+	}
+
+	// Процесс упал, не закрыв журнал: ключи восстанавливаются из записей.
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir, SnapshotEvery: 3})
+	check(o, "после повтора журнала")
+
+	// Снимок тоже хранит ключи.
+	if err := o.Close(); err != nil {
+		t.Fatalf("❌ не удалось закрыть журнал: %v", err)
+	}
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir, SnapshotEvery: 3})
+	defer o.Close()
+	check(o, "после снимка")
+}
@@ -0,0 +1,81 @@
+package orchestrator
+
+import (
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrTaskNotFound      = errors.New("задача не найдена")
+	ErrTaskNotInProgress = errors.New("задача не выполняется")
+	ErrNotLeaseHolder    = errors.New("задача выдана другому агенту")
+)
+
+// NextTasks выдаёт агенту до limit задач за один вызов.
+func (o *Orchestrator) NextTasks(agent string, limit int) []models.Task {
+	if !o.hasTask(agent) {
+		return []models.Task{}
+	}
+
+	now := time.Now()
+	res := o.propose(command{Type: cmdTakeTasks, At: now, Agent: agent, Limit: limit, LeaseUntil: now.Add(o.leaseTimeout)})
+	if res.Err != nil {
+		o.logger.Warn("ошибка при выдаче задач", logging.AgentID(agent), logging.Err(res.Err))
+		return []models.Task{}
+	}
+	return res.Tasks
+}
+
+// RecordResults записывает пачку результатов, присланных агентом. Ошибка
+// в одном результате не мешает записать остальные.
+func (o *Orchestrator) RecordResults(agent string, results []models.TaskResult) []models.Ack {
+	errs, err := o.recordResults(agent, results)
+	return newAcks(results, errs, err)
+}
+
+// newAcks формирует подтверждения результатов. err — ошибка записи всей пачки.
+func newAcks(results []models.TaskResult, errs []error, err error) []models.Ack {
+	acks := make([]models.Ack, 0, len(results))
+	for i, res := range results {
+		ack := models.Ack{ID: res.ID, Status: models.AckOK}
+		resErr := err
+		if resErr == nil {
+			resErr = errs[i]
+		}
+		if resErr != nil {
+			ack.Status = models.AckError
+			ack.Error = resErr.Error()
+		}
+		acks = append(acks, ack)
+	}
+	return acks
+}
+
+// recordResults записывает результаты и возвращает ошибку для каждого из них.
+func (o *Orchestrator) recordResults(agent string, results []models.TaskResult) ([]error, error) {
+	res := o.propose(command{Type: cmdRecordResults, At: time.Now(), Agent: agent, Results: results})
+	return res.Errs, res.Err
+}
+
+// handleTaskBatch обрабатывает GET /internal/task?limit=N.
+func (o *Orchestrator) handleTaskBatch(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		http.Error(w, "❌ Неверный параметр limit", http.StatusBadRequest)
+		return
+	}
+
+	tasks := o.NextTasks(agentName(r), min(limit, models.MaxBatchSize))
+	if len(tasks) == 0 {
+		http.Error(w, "❌ Нет доступных задач", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]models.Task{"tasks": tasks})
+}
@@ -0,0 +1,124 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTaskBatch(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	for _, expr := range []string{"1+1", "2+2", "3+3"} {
+		o.AddExpression(expr)
+	}
+
+	rec := httptest.NewRecorder()
+	o.HandleTask(rec, httptest.NewRequest(http.MethodGet, "/internal/task?limit=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("❌ ожидали код 200, а получили %d", rec.Code)
+	}
+
+	var batch struct {
+		Tasks []models.Task `json:"tasks"`
+	}
+	json.NewDecoder(rec.Body).Decode(&batch)
+	if len(batch.Tasks) != 2 {
+		t.Fatalf("❌ ожидали 2 задачи, а получили %d", len(batch.Tasks))
+	}
+
+	body := fmt.Sprintf(`{"results": [{"id": %d, "result": 2}, {"id": %d, "result": 4}, {"id": 3, "result": 6}, {"id": 42, "result": 0}]}`,
+		batch.Tasks[0].ID, batch.Tasks[1].ID)
+	rec = httptest.NewRecorder()
+	o.HandleTask(rec, httptest.NewRequest(http.MethodPost, "/internal/task", strings.NewReader(body)))
+
+	var response struct {
+		Acks []models.Ack `json:"acks"`
+	}
+	json.NewDecoder(rec.Body).Decode(&response)
+
+	tests := []struct {
+		name       string
+		wantStatus string
+	}{
+		{"Первый результат", models.AckOK},
+		{"Второй результат", models.AckOK},
+		{"Задача не выдана", models.AckError},
+		{"Неизвестная задача", models.AckError},
+	}
+
+	if len(response.Acks) != len(tests) {
+		t.Fatalf("❌ ожидали %d подтверждений, а получили %+v", len(tests), response.Acks)
+	}
+	for i, tt := range tests {
+		if response.Acks[i].Status != tt.wantStatus {
+			t.Fatalf("❌ %s: ожидали статус '%s', а получили %+v", tt.name, tt.wantStatus, response.Acks[i])
+		}
+		fmt.Printf("✅ %s: %s\n", tt.name, response.Acks[i].Status)
+	}
+
+	for id, want := range map[int]string{1: orchestrator.StatusDone, 2: orchestrator.StatusDone, 3: orchestrator.StatusPending} {
+		if expr, _ := o.GetExpression(id); expr.Status != want {
+			t.Fatalf("❌ выражение %d: ожидали статус '%s', а получили '%s'", id, want, expr.Status)
+		}
+	}
+}
+
+func TestTaskResultError(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	id, _ := o.AddExpression("(1+2)*(4/2)")
+
+	// Агент выполнил первую задачу и сообщил об ошибке во второй.
+	tasks := o.NextTasks("agent-1", models.MaxBatchSize)
+	acks := o.RecordResults("agent-1", []models.TaskResult{
+		{ID: tasks[0].ID, Result: 3},
+		{ID: tasks[1].ID, Error: "деление на ноль"},
+	})
+	for _, ack := range acks {
+		if ack.Status != models.AckOK {
+			t.Fatalf("❌ ожидали, что результаты приняты, а получили %+v", acks)
+		}
+	}
+
+	expr, _ := o.GetExpression(id)
+	if expr.Status != orchestrator.StatusError || !strings.Contains(expr.Error, "деление на ноль") {
+		t.Fatalf("❌ ожидали статус error с причиной, а получили %+v", expr)
+	}
+	fmt.Printf("✅ Выражение завершено с ошибкой: %s\n", expr.Error)
+
+	infos, _ := o.GetExpressionTasks(id)
+	want := []string{orchestrator.TaskDone, orchestrator.TaskFailed, orchestrator.TaskCancelled}
+	for i, info := range infos {
+		if info.Status != want[i] {
+			t.Fatalf("❌ задача №%d: ожидали статус '%s', а получили %+v", i+1, want[i], info)
+		}
+	}
+	if infos[1].Error != "деление на ноль" {
+		t.Fatalf("❌ ожидали ошибку задачи, а получили %+v", infos[1])
+	}
+	fmt.Println("✅ Ошибка задачи записана, остальные задачи сняты")
+}
+
+func TestTaskBatchUnavailable(t *testing.T) {
+	o := openWAL(t, orchestrator.WALConfig{Dir: t.TempDir()})
+	o.AddExpression("1+1")
+	tasks := o.NextTasks("agent-1", 1)
+
+	// Журнал закрыт: пачку не удаётся записать, агент должен прислать её повторно.
+	o.Close()
+
+	body := fmt.Sprintf(`{"results": [{"id": %d, "result": 2}]}`, tasks[0].ID)
+	req := httptest.NewRequest(http.MethodPost, "/internal/task", strings.NewReader(body))
+	req.Header.Set(models.AgentIDHeader, "agent-1")
+	rec := httptest.NewRecorder()
+	o.HandleTask(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("❌ ожидали код 503, а получили %d %s", rec.Code, rec.Body)
+	}
+	fmt.Println("✅ Незаписанная пачка результатов отклонена с кодом 503")
+}
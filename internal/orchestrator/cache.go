@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"container/list"
+	"os"
+	"time"
+)
+
+// cacheEntry — результат выражения или подвыражения по его канонической записи.
+type cacheEntry struct {
+	Key      string    `json:"key"`
+	Result   float64   `json:"result"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// resultCache — LRU-кэш результатов с ограниченным размером и временем жизни записей.
+// Меняется только при применении команд и со временем из команды, поэтому
+// на всех репликах и после восстановления из журнала он одинаков.
+type resultCache struct {
+	size int
+	ttl  time.Duration
+
+	items map[string]*list.Element
+	// order — записи от недавно использованных к давно использованным.
+	order *list.List
+}
+
+// newResultCache создаёт кэш на size записей. Если size равен нулю, кэш отключён.
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	return &resultCache{size: size, ttl: ttl, items: make(map[string]*list.Element), order: list.New()}
+}
+
+// newResultCacheFromEnv создаёт кэш размером RESULT_CACHE_SIZE записей (по умолчанию
+// 10000, 0 отключает кэш) со временем жизни записей RESULT_CACHE_TTL_MS (по умолчанию 10 минут).
+func newResultCacheFromEnv() *resultCache {
+	size := getEnvInt("RESULT_CACHE_SIZE", 10_000)
+	if os.Getenv("RESULT_CACHE_SIZE") == "0" {
+		size = 0
+	}
+	ttl := time.Duration(getEnvInt("RESULT_CACHE_TTL_MS", 600_000)) * time.Millisecond
+	return newResultCache(size, ttl)
+}
+
+// lookup возвращает результат, если он есть в кэше и не устарел к моменту now.
+// Кэш при этом не меняется: реплика ищет результаты до того, как команда
+// попадёт в журнал.
+func (c *resultCache) lookup(key string, now time.Time) (float64, bool) {
+	elem, exists := c.items[key]
+	if !exists {
+		return 0, false
+	}
+	entry := elem.Value.(cacheEntry)
+	if now.Sub(entry.StoredAt) > c.ttl {
+		return 0, false
+	}
+	return entry.Result, true
+}
+
+// touch отмечает запись как недавно использованную.
+func (c *resultCache) touch(key string) {
+	if elem, exists := c.items[key]; exists {
+		c.order.MoveToFront(elem)
+	}
+}
+
+func (c *resultCache) put(key string, result float64, now time.Time) {
+	if c.size == 0 {
+		return
+	}
+
+	entry := cacheEntry{Key: key, Result: result, StoredAt: now}
+	if elem, exists := c.items[key]; exists {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(cacheEntry).Key)
+	}
+}
+
+// countCacheHit учитывает попадание в кэш: kind — expression или task. Вызывается под o.mu.
+func (o *Orchestrator) countCacheHit(kind string) {
+	if !o.quiet {
+		o.metrics.cacheHits.WithLabelValues(kind).Inc()
+	}
+}
+
+// entries возвращает записи от давно использованных к недавно использованным.
+func (c *resultCache) entries() []cacheEntry {
+	entries := make([]cacheEntry, 0, c.order.Len())
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		entries = append(entries, elem.Value.(cacheEntry))
+	}
+	return entries
+}
+
+// restore заменяет содержимое кэша записями в порядке entries.
+func (c *resultCache) restore(entries []cacheEntry) {
+	c.items = make(map[string]*list.Element, len(entries))
+	c.order.Init()
+	for _, entry := range entries {
+		c.put(entry.Key, entry.Result, entry.StoredAt)
+	}
+}
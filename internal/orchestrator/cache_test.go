@@ -0,0 +1,133 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// solve выполняет все задачи в очереди, вычисляя их так же, как агент.
+func solve(o *orchestrator.Orchestrator) {
+	for {
+		tasks := o.NextTasks("agent-1", models.MaxBatchSize)
+		if len(tasks) == 0 {
+			return
+		}
+		results := make([]models.TaskResult, len(tasks))
+		for i, task := range tasks {
+			var result float64
+			switch task.Operation {
+			case "+":
+				result = task.Arg1 + task.Arg2
+			case "-":
+				result = task.Arg1 - task.Arg2
+			case "*":
+				result = task.Arg1 * task.Arg2
+			case "/":
+				result = task.Arg1 / task.Arg2
+			}
+			results[i] = models.TaskResult{ID: task.ID, Result: result}
+		}
+		o.RecordResults("agent-1", results)
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+
+	o.AddExpression("(1+2)*(3+4)")
+	solve(o)
+
+	// Та же запись с другими пробелами и порядком множителей.
+	id, _ := o.AddExpression("(4 + 3) * (2 + 1)")
+	expr, _ := o.GetExpression(id)
+	if expr.Status != orchestrator.StatusDone || expr.Result != 21 || !expr.Cached || expr.TasksTotal != 3 || expr.TasksDone != 3 {
+		t.Fatalf("❌ ожидали результат из кэша, а получили %+v", expr)
+	}
+	fmt.Println("✅ Повторное выражение завершено сразу")
+
+	// Подвыражение 1+2 уже вычислялось: в очередь попадает только умножение.
+	id, _ = o.AddExpression("(2+1)*5")
+	tasks, _ := o.GetExpressionTasks(id)
+	if !tasks[0].Cached || tasks[0].Status != orchestrator.TaskDone || *tasks[0].Result != 3 || tasks[1].Status != orchestrator.TaskQueued {
+		t.Fatalf("❌ ожидали переиспользование подвыражения, а получили %+v", tasks)
+	}
+	if queued := o.NextTasks("agent-1", 10); len(queued) != 1 || queued[0].Operation != "*" {
+		t.Fatalf("❌ ожидали в очереди только умножение, а получили %+v", queued)
+	}
+	fmt.Println("✅ Результат подвыражения переиспользован")
+
+	// Выражение, все задачи которого уже вычислялись, тоже завершается сразу.
+	id, _ = o.AddExpression("1+2")
+	if expr, _ := o.GetExpression(id); expr.Status != orchestrator.StatusDone || expr.Result != 3 {
+		t.Fatalf("❌ ожидали результат из кэша задач, а получили %+v", expr)
+	}
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	t.Run("Время жизни", func(t *testing.T) {
+		t.Setenv("RESULT_CACHE_TTL_MS", "1")
+		o := orchestrator.NewOrchestrator()
+
+		o.AddExpression("2+2")
+		solve(o)
+		time.Sleep(5 * time.Millisecond)
+
+		id, _ := o.AddExpression("2+2")
+		if expr, _ := o.GetExpression(id); expr.Status == orchestrator.StatusDone {
+			t.Fatalf("❌ ожидали, что устаревший результат не будет использован: %+v", expr)
+		}
+		fmt.Println("✅ Устаревший результат не используется")
+	})
+
+	t.Run("Размер", func(t *testing.T) {
+		// У выражения из одной операции ключи выражения и задачи совпадают: это одна запись.
+		t.Setenv("RESULT_CACHE_SIZE", "1")
+		o := orchestrator.NewOrchestrator()
+
+		o.AddExpression("2+2")
+		solve(o)
+		o.AddExpression("3+3")
+		solve(o)
+
+		id, _ := o.AddExpression("2+2")
+		if expr, _ := o.GetExpression(id); expr.Status == orchestrator.StatusDone {
+			t.Fatalf("❌ ожидали, что давно использованный результат будет вытеснен: %+v", expr)
+		}
+		id, _ = o.AddExpression("3+3")
+		if expr, _ := o.GetExpression(id); expr.Status != orchestrator.StatusDone {
+			t.Fatalf("❌ ожидали результат из кэша, а получили %+v", expr)
+		}
+		fmt.Println("✅ Давно использованный результат вытеснен")
+	})
+
+	t.Run("Отключён", func(t *testing.T) {
+		t.Setenv("RESULT_CACHE_SIZE", "0")
+		o := orchestrator.NewOrchestrator()
+
+		o.AddExpression("2+2")
+		solve(o)
+		id, _ := o.AddExpression("2+2")
+		if expr, _ := o.GetExpression(id); expr.Status == orchestrator.StatusDone {
+			t.Fatalf("❌ ожидали, что кэш отключён: %+v", expr)
+		}
+	})
+}
+
+func TestResultCacheRestore(t *testing.T) {
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir})
+	o.AddExpression("6*7")
+	solve(o)
+	o.Close()
+
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir})
+	defer o.Close()
+	id, _ := o.AddExpression("7*6")
+	if expr, _ := o.GetExpression(id); expr.Status != orchestrator.StatusDone || expr.Result != 42 {
+		t.Fatalf("❌ ожидали, что кэш восстановлен из снимка: %+v", expr)
+	}
+	fmt.Println("✅ Кэш восстановлен из снимка")
+}
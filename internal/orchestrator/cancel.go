@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrExpressionNotFound = errors.New("выражение не найдено")
+	// ErrExpressionFinished — выражение уже завершено, отменить его нельзя.
+	ErrExpressionFinished = errors.New("выражение уже завершено")
+)
+
+// CancelExpression отменяет выражение: его задачи снимаются из очереди,
+// а результаты задач, уже выданных агентам, больше не принимаются.
+func (o *Orchestrator) CancelExpression(id int) error {
+	return o.propose(command{Type: cmdCancelExpression, At: time.Now(), ExpressionID: id}).Err
+}
+
+// cancelExpression переводит выражение в статус cancelled. Вызывается под o.mu.
+func (o *Orchestrator) cancelExpression(id int, now time.Time) error {
+	expr, err := o.cancellable(id)
+	if err != nil {
+		return err
+	}
+
+	o.cancelTasks(expr)
+	o.finalize(expr, StatusCancelled, now)
+	o.logger.Info("выражение отменено", logging.ExpressionID(id))
+	return nil
+}
+
+// cancellable возвращает выражение, если его ещё можно отменить. Вызывается под o.mu.
+func (o *Orchestrator) cancellable(id int) (*Expression, error) {
+	expr, exists := o.expressions[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %d", ErrExpressionNotFound, id)
+	}
+	if isFinalStatus(expr.Status) {
+		return nil, fmt.Errorf("%w: %d", ErrExpressionFinished, id)
+	}
+	return expr, nil
+}
+
+// HandleCancelExpression обрабатывает POST /api/v1/expressions/{id}/cancel.
+func (o *Orchestrator) HandleCancelExpression(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "❌ Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := o.CancelExpression(id)
+	switch {
+	case stateUnavailable(err):
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, ErrExpressionNotFound):
+		http.Error(w, "❌ Выражение не найдено", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrExpressionFinished):
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusConflict)
+		return
+	}
+
+	expr, _ := o.GetExpression(id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expr)
+}
@@ -0,0 +1,61 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCancelExpression(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	id, _ := o.AddExpression("(1+2)*(3+4)")
+	leased := o.NextTasks("agent-1", 1)
+
+	cancel := func(id int) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		o.HandleGetExpressionByID(rec, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/expressions/%d/cancel", id), nil))
+		return rec
+	}
+
+	rec := cancel(id)
+	var expr orchestrator.Expression
+	json.NewDecoder(rec.Body).Decode(&expr)
+	if rec.Code != http.StatusOK || expr.Status != orchestrator.StatusCancelled || expr.FinishedAt == nil {
+		t.Fatalf("❌ ожидали отменённое выражение, а получили %d %+v", rec.Code, expr)
+	}
+	fmt.Println("✅ Выражение отменено")
+
+	tasks, _ := o.GetExpressionTasks(id)
+	for _, task := range tasks {
+		if task.Status != orchestrator.TaskCancelled {
+			t.Fatalf("❌ ожидали, что все задачи сняты, а получили %+v", task)
+		}
+	}
+	if queued := o.NextTasks("agent-1", 10); len(queued) != 0 {
+		t.Fatalf("❌ ожидали пустую очередь, а получили %+v", queued)
+	}
+	acks := o.RecordResults("agent-1", []models.TaskResult{{ID: leased[0].ID, Result: 3}})
+	if acks[0].Status != models.AckError {
+		t.Fatalf("❌ ожидали, что результат отменённой задачи не принят, а получили %+v", acks[0])
+	}
+	fmt.Println("✅ Задачи отменённого выражения сняты")
+
+	tests := []struct {
+		name       string
+		id         int
+		wantStatus int
+	}{
+		{"Повторная отмена", id, http.StatusConflict},
+		{"Несуществующее выражение", 42, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := cancel(tt.id); rec.Code != tt.wantStatus {
+			t.Fatalf("❌ %s: ожидали код %d, а получили %d", tt.name, tt.wantStatus, rec.Code)
+		}
+		fmt.Printf("✅ %s: %d\n", tt.name, tt.wantStatus)
+	}
+}
@@ -0,0 +1,282 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/logging"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// ForwardedHeader отмечает запрос, уже переданный репликой лидеру, чтобы не гонять его по кругу.
+const ForwardedHeader = "X-Calc-Forwarded-By"
+
+// ClientAddrHeader передаёт лидеру адрес клиента, запрос которого переслала реплика.
+// Лидер доверяет ему, только если PeerSignatureHeader содержит его подпись общим
+// для реплик секретом PEER_SECRET.
+const (
+	ClientAddrHeader    = "X-Calc-Client-Addr"
+	PeerSignatureHeader = "X-Calc-Peer-Signature"
+)
+
+var ErrNoLeader = errors.New("лидер не выбран")
+
+// Node — реплика оркестратора и адреса, по которым другие реплики передают ей запросы.
+type Node struct {
+	ID          string
+	PublicURL   string
+	InternalURL string
+}
+
+type election struct {
+	store LeaseStore
+	node  Node
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	leader Lease
+}
+
+// WithElection включает выбор лидера среди реплик, разделяющих store.
+// Состояние и планировщик живут только на лидере; остальные реплики
+// передают ему запросы к API. Лидер продлевает аренду каждые ttl/3.
+// Чтобы состояние пережило смену лидера, журнал (OpenWAL) должен лежать
+// на общем для реплик томе.
+func WithElection(store LeaseStore, node Node, ttl time.Duration) Option {
+	return func(o *Orchestrator) {
+		o.election = &election{store: store, node: node, ttl: ttl}
+	}
+}
+
+// electionFromEnv включает выбор лидера, если задан LEADER_LEASE_FILE — файл аренды
+// на общем томе. Реплика представляется NODE_ID и адресами ADVERTISE_PUBLIC_URL
+// и ADVERTISE_INTERNAL_URL; срок аренды — LEADER_LEASE_TTL_MS (по умолчанию 10000).
+func electionFromEnv() Option {
+	path := os.Getenv("LEADER_LEASE_FILE")
+	if path == "" {
+		return func(*Orchestrator) {}
+	}
+
+	ttl := time.Duration(getEnvInt("LEADER_LEASE_TTL_MS", 10_000)) * time.Millisecond
+	return WithElection(NewFileLeaseStore(path), nodeFromEnv(), ttl)
+}
+
+// nodeFromEnv описывает реплику переменными NODE_ID, ADVERTISE_PUBLIC_URL и ADVERTISE_INTERNAL_URL.
+func nodeFromEnv() Node {
+	hostname, _ := os.Hostname()
+	return Node{
+		ID:          getEnvString("NODE_ID", fmt.Sprintf("%s-%d", hostname, os.Getpid())),
+		PublicURL:   os.Getenv("ADVERTISE_PUBLIC_URL"),
+		InternalURL: os.Getenv("ADVERTISE_INTERNAL_URL"),
+	}
+}
+
+// clustered сообщает, работает ли оркестратор в составе нескольких реплик.
+func (o *Orchestrator) clustered() bool {
+	return o.raft != nil || o.election != nil
+}
+
+// self возвращает описание этой реплики.
+func (o *Orchestrator) self() Node {
+	if o.raft != nil {
+		return o.raft.node
+	}
+	if o.election != nil {
+		return o.election.node
+	}
+	return Node{}
+}
+
+// Leader возвращает адреса действующего лидера, если он известен.
+func (o *Orchestrator) Leader() (Lease, bool) {
+	if o.raft != nil {
+		return o.raft.currentLeader()
+	}
+	if o.election == nil {
+		return Lease{}, false
+	}
+	o.election.mu.Lock()
+	defer o.election.mu.Unlock()
+
+	lease := o.election.leader
+	return lease, lease.Holder != "" && time.Now().Before(lease.ExpiresAt)
+}
+
+// IsLeader сообщает, является ли реплика лидером. Без выбора лидера оркестратор всегда лидер.
+func (o *Orchestrator) IsLeader() bool {
+	if o.raft != nil {
+		return o.raft.isLeader()
+	}
+	if o.election == nil {
+		return true
+	}
+	lease, ok := o.Leader()
+	return ok && lease.Holder == o.election.node.ID
+}
+
+// Elect выполняет один раунд выбора: захватывает или продлевает аренду,
+// если это возможно, и запоминает текущего лидера.
+func (o *Orchestrator) Elect() error {
+	e := o.election
+	if e == nil {
+		return nil
+	}
+
+	wasLeader := o.IsLeader()
+	lease, err := e.store.TryAcquire(Lease{
+		Holder:      e.node.ID,
+		PublicURL:   e.node.PublicURL,
+		InternalURL: e.node.InternalURL,
+		ExpiresAt:   time.Now().Add(e.ttl),
+	})
+	if err != nil {
+		// Старая аренда остаётся в силе до истечения: лидер, потерявший
+		// доступ к хранилищу, перестанет им быть, когда аренда истечёт.
+		o.logger.Warn("ошибка при обращении к хранилищу аренды", logging.Err(err))
+	} else {
+		if lease.Holder == e.node.ID && !wasLeader && o.wal != nil {
+			// Новый лидер загружает состояние из общего журнала до того,
+			// как начнёт обслуживать запросы
+			if err = o.wal.load(); err != nil {
+				o.logger.Error("ошибка восстановления из журнала", logging.Err(err))
+				e.store.Release(e.node.ID)
+				lease = Lease{}
+			}
+		}
+		e.mu.Lock()
+		e.leader = lease
+		e.mu.Unlock()
+	}
+
+	isLeader := o.IsLeader()
+	o.metrics.leader.Set(boolToFloat(isLeader))
+	switch {
+	case isLeader && !wasLeader:
+		o.logger.Info("реплика стала лидером", "node_id", e.node.ID)
+	case !isLeader && wasLeader:
+		o.logger.Warn("реплика больше не лидер", "node_id", e.node.ID, "leader", lease.Holder)
+		if o.wal != nil {
+			o.wal.release(false)
+		}
+	}
+	return err
+}
+
+// Resign освобождает аренду, чтобы другая реплика стала лидером, не дожидаясь её истечения.
+// В Raft-кластере лидерство передаётся другой реплике.
+func (o *Orchestrator) Resign() {
+	if o.raft != nil && o.IsLeader() {
+		if err := o.raft.r.LeadershipTransfer().Error(); err != nil {
+			o.logger.Warn("ошибка при передаче лидерства", logging.Err(err))
+		}
+		return
+	}
+	if o.election == nil || !o.IsLeader() {
+		return
+	}
+	// Журнал передаётся следующему лидеру вместе со снимком
+	if o.wal != nil {
+		if err := o.wal.release(true); err != nil {
+			o.logger.Warn("ошибка при сохранении снимка", logging.Err(err))
+		}
+	}
+	if err := o.election.store.Release(o.election.node.ID); err != nil {
+		o.logger.Warn("ошибка при освобождении аренды", logging.Err(err))
+		return
+	}
+	o.election.mu.Lock()
+	o.election.leader = Lease{}
+	o.election.mu.Unlock()
+	o.logger.Info("реплика сложила полномочия лидера", "node_id", o.election.node.ID)
+}
+
+func (o *Orchestrator) runElection() {
+	o.Elect()
+
+	ticker := time.NewTicker(o.election.ttl / 3)
+	defer ticker.Stop()
+	for range ticker.C {
+		o.Elect()
+	}
+}
+
+// localPaths обслуживаются самой репликой, даже если она не лидер.
+var localPaths = map[string]bool{"/healthz": true, "/readyz": true, "/version": true, "/metrics": true}
+
+// leaderProxy передаёт запросы к API лидеру, если реплика им не является.
+// internal выбирает адрес лидера: внутренний API или публичный.
+func (o *Orchestrator) leaderProxy(next http.Handler, internal bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.IsLeader() || localPaths[r.URL.Path] {
+			next.ServeHTTP(w, o.forwardedClient(r))
+			return
+		}
+
+		lease, ok := o.Leader()
+		if !ok || r.Header.Get(ForwardedHeader) != "" {
+			http.Error(w, fmt.Sprintf("❌ %v", ErrNoLeader), http.StatusServiceUnavailable)
+			return
+		}
+
+		target := lease.PublicURL
+		if internal {
+			target = lease.InternalURL
+		}
+		u, err := url.Parse(target)
+		if err != nil || target == "" {
+			http.Error(w, fmt.Sprintf("❌ Неверный адрес лидера: %q", target), http.StatusBadGateway)
+			return
+		}
+
+		proxy := httputil.NewSingleHostReverseProxy(u)
+		proxy.FlushInterval = -1 // потоки событий (SSE) передаются без буферизации
+		r.Header.Set(ForwardedHeader, o.self().ID)
+		r.Header.Del(ClientAddrHeader)
+		r.Header.Del(PeerSignatureHeader)
+		if len(o.peerSecret) > 0 {
+			// Лимиты и квоты лидер считает по адресу клиента, а не реплики.
+			r.Header.Set(ClientAddrHeader, r.RemoteAddr)
+			r.Header.Set(PeerSignatureHeader, o.peerSignature(r.RemoteAddr))
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// peerSignature подписывает адрес клиента секретом PEER_SECRET.
+func (o *Orchestrator) peerSignature(clientAddr string) string {
+	mac := hmac.New(sha256.New, o.peerSecret)
+	mac.Write([]byte(clientAddr))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// forwardedClient подставляет в запрос, переданный другой репликой, адрес
+// исходного клиента. Неподписанный адрес игнорируется: его мог прислать сам клиент.
+func (o *Orchestrator) forwardedClient(r *http.Request) *http.Request {
+	addr, signature := r.Header.Get(ClientAddrHeader), r.Header.Get(PeerSignatureHeader)
+	if addr == "" && signature == "" {
+		return r
+	}
+	r.Header.Del(ClientAddrHeader)
+	r.Header.Del(PeerSignatureHeader)
+	if addr == "" || len(o.peerSecret) == 0 || !hmac.Equal([]byte(signature), []byte(o.peerSignature(addr))) {
+		return r
+	}
+
+	forwarded := r.WithContext(r.Context())
+	forwarded.RemoteAddr = addr
+	return forwarded
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,238 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replica — оркестратор с поднятыми публичным и внутренним серверами.
+type replica struct {
+	o                *orchestrator.Orchestrator
+	public, internal *httptest.Server
+}
+
+func startReplica(t *testing.T, id string, store orchestrator.LeaseStore) *replica {
+	r := &replica{}
+	r.public = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.o.PublicHandler().ServeHTTP(w, req)
+	}))
+	r.internal = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.o.InternalHandler().ServeHTTP(w, req)
+	}))
+	t.Cleanup(r.public.Close)
+	t.Cleanup(r.internal.Close)
+
+	node := orchestrator.Node{ID: id, PublicURL: r.public.URL, InternalURL: r.internal.URL}
+	r.o = orchestrator.NewOrchestrator(orchestrator.WithElection(store, node, time.Minute))
+	return r
+}
+
+func TestLeaderElection(t *testing.T) {
+	store := orchestrator.NewMemoryLeaseStore()
+	replicas := []*replica{
+		startReplica(t, "node-1", store),
+		startReplica(t, "node-2", store),
+		startReplica(t, "node-3", store),
+	}
+
+	for _, r := range replicas {
+		if resp, _ := http.Get(r.public.URL + "/readyz"); resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("❌ без лидера /readyz: ожидали 503, а получили %d", resp.StatusCode)
+		}
+		r.o.Elect()
+	}
+
+	leaders := 0
+	for _, r := range replicas {
+		if r.o.IsLeader() {
+			leaders++
+		}
+	}
+	if leaders != 1 || !replicas[0].o.IsLeader() {
+		t.Fatalf("❌ ожидали единственного лидера node-1, а лидеров %d", leaders)
+	}
+	fmt.Println("✅ Лидером стала одна реплика")
+
+	// Выражение, отправленное ведомой реплике, попадает к лидеру.
+	resp, err := http.Post(replicas[1].public.URL+"/api/v1/calculate", "application/json", strings.NewReader(`{"expression": "2+2"}`))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("❌ ожидали 201 через ведомую реплику, а получили %v %v", resp, err)
+	}
+	var created map[string]string
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+
+	if _, ok := replicas[0].o.GetExpression(1); !ok || created["id"] != "1" {
+		t.Fatalf("❌ выражение не попало к лидеру: %v", created)
+	}
+
+	resp, err = http.Get(replicas[2].public.URL + "/api/v1/expressions/1")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("❌ ожидали 200 при чтении через ведомую реплику, а получили %v %v", resp, err)
+	}
+	resp.Body.Close()
+	fmt.Println("✅ Ведомые реплики передают запросы лидеру")
+
+	// Агент забирает задачу у ведомой реплики по внутреннему API.
+	resp, err = http.Get(replicas[2].internal.URL + "/internal/task")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("❌ ожидали задачу через ведомую реплику, а получили %v %v", resp, err)
+	}
+	resp.Body.Close()
+	fmt.Println("✅ Внутренний API ведомой реплики передаётся лидеру")
+
+	// Лидер упал и больше не продлевает аренду: после её истечения лидером становится другая реплика.
+	store.Now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	replicas[1].o.Elect()
+	store.Now = time.Now
+	replicas[2].o.Elect()
+	replicas[0].o.Elect()
+
+	if !replicas[1].o.IsLeader() || replicas[0].o.IsLeader() || replicas[2].o.IsLeader() {
+		t.Fatal("❌ ожидали, что лидером станет node-2")
+	}
+	if lease, _ := replicas[2].o.Leader(); lease.PublicURL != replicas[1].public.URL {
+		t.Fatalf("❌ ведомая реплика не знает адрес нового лидера: %+v", lease)
+	}
+	fmt.Println("✅ После истечения аренды лидером стала другая реплика")
+
+	// При штатной остановке лидер освобождает аренду сразу.
+	replicas[1].o.Resign()
+	replicas[2].o.Elect()
+	if !replicas[2].o.IsLeader() {
+		t.Fatal("❌ ожидали, что после отставки лидером станет node-3")
+	}
+	fmt.Println("✅ После отставки лидер сменился без ожидания")
+}
+
+func TestFileLeaseStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.json")
+	a, b := orchestrator.NewFileLeaseStore(path), orchestrator.NewFileLeaseStore(path)
+	expires := time.Now().Add(time.Minute)
+
+	lease, err := a.TryAcquire(orchestrator.Lease{Holder: "node-1", PublicURL: "http://node-1", ExpiresAt: expires})
+	if err != nil || lease.Holder != "node-1" {
+		t.Fatalf("❌ ожидали захват аренды node-1, а получили %+v %v", lease, err)
+	}
+
+	lease, err = b.TryAcquire(orchestrator.Lease{Holder: "node-2", ExpiresAt: expires})
+	if err != nil || lease.Holder != "node-1" || lease.PublicURL != "http://node-1" {
+		t.Fatalf("❌ ожидали, что аренда останется у node-1, а получили %+v %v", lease, err)
+	}
+
+	if err := b.Release("node-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Release("node-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	lease, err = b.TryAcquire(orchestrator.Lease{Holder: "node-2", ExpiresAt: expires})
+	if err != nil || lease.Holder != "node-2" {
+		t.Fatalf("❌ ожидали захват освобождённой аренды node-2, а получили %+v %v", lease, err)
+	}
+
+	lease, _ = a.TryAcquire(orchestrator.Lease{Holder: "node-1", ExpiresAt: expires})
+	if lease.Holder != "node-2" {
+		t.Fatalf("❌ ожидали, что аренда останется у node-2, а получили %+v", lease)
+	}
+	fmt.Println("✅ Файловое хранилище аренды")
+}
+
+func TestLeaderElectionWAL(t *testing.T) {
+	dir := t.TempDir()
+	store := orchestrator.NewMemoryLeaseStore()
+	replicas := []*replica{startReplica(t, "node-1", store), startReplica(t, "node-2", store)}
+	for _, r := range replicas {
+		if err := r.o.OpenWAL(orchestrator.WALConfig{Dir: dir}); err != nil {
+			t.Fatalf("❌ не удалось открыть журнал: %v", err)
+		}
+		t.Cleanup(func() { r.o.Close() })
+		r.o.Elect()
+	}
+	first, second := replicas[0].o, replicas[1].o
+
+	first.AddExpression("1+1")
+	first.AddExpression("2+2")
+	tasks := first.NextTasks("agent-1", 1)
+	first.RecordResults("agent-1", []models.TaskResult{{ID: tasks[0].ID, Result: 2}})
+
+	// Лидер упал: новый лидер загружает состояние из общего журнала.
+	store.Now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	second.Elect()
+	store.Now = time.Now
+	if !second.IsLeader() {
+		t.Fatal("❌ ожидали, что лидером станет node-2")
+	}
+
+	if expr, _ := second.GetExpression(1); expr.Status != orchestrator.StatusDone || expr.Result != 2 {
+		t.Fatalf("❌ новый лидер не знает о вычисленном выражении: %+v", expr)
+	}
+	if id, _ := second.AddExpression("3+3"); id != 3 {
+		t.Fatalf("❌ ожидали id 3, а получили %d", id)
+	}
+	fmt.Println("✅ Новый лидер продолжил с состояния прежнего")
+
+	// Прежний лидер узнаёт о потере аренды и больше не пишет в журнал.
+	first.Elect()
+	if _, err := first.AddExpression("4+4"); err == nil {
+		t.Fatal("❌ ожидали, что бывший лидер не запишет изменение")
+	}
+
+	// При отставке лидер сохраняет снимок, и следующий лидер его загружает.
+	second.Resign()
+	first.Elect()
+	if !first.IsLeader() || len(first.GetAllExpressions()) != 3 {
+		t.Fatalf("❌ ожидали, что node-1 станет лидером с тремя выражениями, а получили %d", len(first.GetAllExpressions()))
+	}
+	fmt.Println("✅ После отставки лидера состояние передано следующему")
+}
+
+func TestForwardedClientRateLimit(t *testing.T) {
+	t.Setenv("PEER_SECRET", "peer-secret")
+	t.Setenv("RATE_LIMIT_RPS", "1")
+	t.Setenv("RATE_LIMIT_BURST", "1")
+
+	store := orchestrator.NewMemoryLeaseStore()
+	leader, follower := startReplica(t, "node-1", store), startReplica(t, "node-2", store)
+	leader.o.Elect()
+	follower.o.Elect()
+
+	tests := []struct {
+		name       string
+		addr       string
+		wantStatus int
+	}{
+		{"Первый клиент", "10.0.0.1:1000", http.StatusCreated},
+		{"Второй клиент за той же репликой", "10.0.0.2:1000", http.StatusCreated},
+		{"Первый клиент сверх лимита", "10.0.0.1:1001", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		if rec := calculateFrom(follower.o.PublicHandler(), tt.addr, "1+1"); rec.Code != tt.wantStatus {
+			t.Fatalf("❌ %s: ожидали код %d, а получили %d", tt.name, tt.wantStatus, rec.Code)
+		}
+		fmt.Printf("✅ %s: код %d\n", tt.name, tt.wantStatus)
+	}
+
+	// Клиент не может сам представиться другим адресом: без подписи заголовок игнорируется.
+	for i, wantStatus := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(`{"expression": "1+1"}`))
+		req.RemoteAddr = "10.0.0.3:1000"
+		req.Header.Set(orchestrator.ClientAddrHeader, fmt.Sprintf("10.0.1.%d:1000", i))
+		req.Header.Set(orchestrator.PeerSignatureHeader, "forged")
+		rec := httptest.NewRecorder()
+		leader.o.PublicHandler().ServeHTTP(rec, req)
+		if rec.Code != wantStatus {
+			t.Fatalf("❌ запрос с поддельным адресом №%d: ожидали код %d, а получили %d", i+1, wantStatus, rec.Code)
+		}
+	}
+	fmt.Println("✅ Неподписанный адрес клиента игнорируется")
+}
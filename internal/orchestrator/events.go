@@ -0,0 +1,183 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Типы событий, которые отправляются подписчикам.
+const (
+	EventStatus   = "status"
+	EventProgress = "progress"
+	EventResult   = "result"
+)
+
+// Event — изменение состояния выражения.
+type Event struct {
+	Type         string   `json:"type"`
+	ExpressionID int      `json:"expression_id"`
+	Status       string   `json:"status"`
+	TasksDone    int      `json:"tasks_done"`
+	TasksTotal   int      `json:"tasks_total"`
+	Result       *float64 `json:"result,omitempty"`
+
+	owner string
+}
+
+const subscriberBuffer = 64
+
+// keepAliveInterval — период отправки комментариев, чтобы прокси не закрывали соединение.
+var keepAliveInterval = 15 * time.Second
+
+type subscriber struct {
+	expressionID int    // 0 — все выражения
+	owner        string // "" — выражения всех владельцев
+	events       chan Event
+}
+
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+
+	// afterSnapshot, если задан, вызывается после снимка состояния выражения,
+	// до начала потока. Тесты изменяют выражение в этот момент.
+	afterSnapshot func()
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[*subscriber]struct{})}
+}
+
+func (b *eventBroker) subscribe(expressionID int, owner string) *subscriber {
+	s := &subscriber{expressionID: expressionID, owner: owner, events: make(chan Event, subscriberBuffer)}
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+func (b *eventBroker) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	delete(b.subscribers, s)
+	b.mu.Unlock()
+}
+
+// publish не блокируется: если подписчик не успевает читать, событие для него теряется.
+func (b *eventBroker) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subscribers {
+		if s.expressionID != 0 && s.expressionID != e.ExpressionID {
+			continue
+		}
+		if s.owner != "" && s.owner != e.owner {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+		}
+	}
+}
+
+// newEvent формирует событие из текущего состояния выражения. Вызывается под o.mu.
+func newEvent(eventType string, expr *Expression) Event {
+	e := Event{
+		Type:         eventType,
+		ExpressionID: expr.ID,
+		Status:       expr.Status,
+		TasksDone:    expr.TasksDone,
+		TasksTotal:   expr.TasksTotal,
+		owner:        expr.Owner,
+	}
+	if eventType == EventResult {
+		result := expr.Result
+		e.Result = &result
+	}
+	return e
+}
+
+// HandleEvents — поток событий по всем выражениям, доступным клиенту.
+func (o *Orchestrator) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	sub := o.events.subscribe(0, ownerFilter(r))
+	defer o.events.unsubscribe(sub)
+
+	o.streamEvents(w, r, sub, 0, nil)
+}
+
+// HandleExpressionEvents — поток событий одного выражения. Первым событием
+// отправляется текущее состояние, поток закрывается после финального статуса.
+func (o *Orchestrator) HandleExpressionEvents(w http.ResponseWriter, r *http.Request, id int) {
+	owner := ownerFilter(r)
+
+	// Подписываемся под o.mu: события публикуются под той же блокировкой,
+	// поэтому ни одно изменение после снимка не потеряется
+	o.mu.Lock()
+	expr, exists := o.expressions[id]
+	if !exists {
+		o.mu.Unlock()
+		http.Error(w, "❌ Выражение не найдено", http.StatusNotFound)
+		return
+	}
+	sub := o.events.subscribe(id, owner)
+	snapshot := newEvent(EventStatus, expr)
+	if isFinalStatus(expr.Status) {
+		snapshot = newEvent(EventResult, expr)
+	}
+	o.mu.Unlock()
+	defer o.events.unsubscribe(sub)
+
+	if o.events.afterSnapshot != nil {
+		o.events.afterSnapshot()
+	}
+	o.streamEvents(w, r, sub, id, &snapshot)
+}
+
+func (o *Orchestrator) streamEvents(w http.ResponseWriter, r *http.Request, sub *subscriber, id int, snapshot *Event) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "❌ Потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if snapshot != nil {
+		writeEvent(w, *snapshot)
+		flusher.Flush()
+		if snapshot.Type == EventResult {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case e := <-sub.events:
+			writeEvent(w, e)
+			flusher.Flush()
+			if id != 0 && e.Type == EventResult {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+}
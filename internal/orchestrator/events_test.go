@@ -0,0 +1,121 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpressionEvents(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(http.HandlerFunc(o.HandleGetExpressionByID))
+	defer srv.Close()
+
+	id, err := o.AddExpression("2+2")
+	if err != nil {
+		t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/expressions/%d/events", srv.URL, id))
+	if err != nil {
+		t.Fatalf("❌ ошибка подключения к потоку событий: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("❌ ожидали Content-Type text/event-stream, а получили '%s'", ct)
+	}
+
+	events := make(chan orchestrator.Event)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var e orchestrator.Event
+			json.Unmarshal([]byte(data), &e)
+			events <- e
+		}
+	}()
+
+	want := []struct {
+		eventType string
+		status    string
+		tasksDone int
+	}{
+		{orchestrator.EventStatus, orchestrator.StatusPending, 0},
+		{orchestrator.EventStatus, orchestrator.StatusInProgress, 0},
+		{orchestrator.EventProgress, orchestrator.StatusInProgress, 1},
+		{orchestrator.EventResult, orchestrator.StatusDone, 1},
+	}
+
+	for i, w := range want {
+		if i == 1 {
+			task := fetchTask(t, o)
+			submitResult(o, task.ID, 4)
+		}
+
+		e, ok := <-events
+		if !ok {
+			t.Fatalf("❌ поток закрыт раньше события №%d", i+1)
+		}
+		if e.Type != w.eventType || e.Status != w.status || e.TasksDone != w.tasksDone || e.TasksTotal != 1 {
+			t.Fatalf("❌ событие №%d: ожидали %s/%s (%d из 1), а получили %+v", i+1, w.eventType, w.status, w.tasksDone, e)
+		}
+		fmt.Printf("✅ событие №%d: %s/%s\n", i+1, e.Type, e.Status)
+	}
+
+	if _, ok := <-events; ok {
+		t.Fatalf("❌ поток не закрыт после финального события")
+	}
+}
+
+func TestExpressionEventsFinishedAfterSnapshot(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(http.HandlerFunc(o.HandleGetExpressionByID))
+	defer srv.Close()
+
+	id, err := o.AddExpression("2+2")
+	if err != nil {
+		t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+	}
+
+	// Выражение завершается после снимка состояния, но до начала потока
+	orchestrator.SetAfterEventsSnapshot(o, func() {
+		for _, task := range o.NextTasks("test-agent", 1) {
+			o.RecordResults("test-agent", []models.TaskResult{{ID: task.ID, Result: 4}})
+		}
+	})
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("%s/api/v1/expressions/%d/events", srv.URL, id))
+	if err != nil {
+		t.Fatalf("❌ ошибка подключения к потоку событий: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var last orchestrator.Event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			json.Unmarshal([]byte(data), &last)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("❌ поток не закрылся после завершения выражения: %v", err)
+	}
+	if last.Type != orchestrator.EventResult || last.Result == nil || *last.Result != 4 {
+		t.Fatalf("❌ ожидали финальное событие с результатом 4, а получили %+v", last)
+	}
+	fmt.Println("✅ Завершение выражения после снимка не потеряно")
+}
@@ -0,0 +1,7 @@
+package orchestrator
+
+// SetAfterEventsSnapshot задаёт функцию, которую поток событий оркестратора o
+// вызывает после снимка состояния выражения.
+func SetAfterEventsSnapshot(o *Orchestrator, f func()) {
+	o.events.afterSnapshot = f
+}
@@ -0,0 +1,41 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpressionMetadata(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	id, err := o.AddExpression("2+2*2")
+	if err != nil {
+		t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+	}
+
+	expr, _ := o.GetExpression(id)
+	if expr.Expression != "2+2*2" || expr.TasksTotal != 2 || expr.StartedAt != nil || expr.CreatedAt.IsZero() {
+		t.Fatalf("❌ неверные метаданные нового выражения: %+v", expr)
+	}
+
+	for i, result := range []float64{4, 6} {
+		task := fetchTask(t, o)
+		body := fmt.Sprintf(`{"id": %d, "result": %g, "compute_time": %d}`, task.ID, result, 5*time.Millisecond)
+		o.HandleTaskResult(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/internal/task", strings.NewReader(body)))
+
+		expr, _ = o.GetExpression(id)
+		if expr.StartedAt == nil || expr.TasksDone != i+1 || expr.ComputeTimeMs != int64(5*(i+1)) {
+			t.Fatalf("❌ после задачи №%d неверные метаданные: %+v", i+1, expr)
+		}
+	}
+
+	if expr.Status != orchestrator.StatusDone || expr.FinishedAt == nil || expr.FinishedAt.Before(*expr.StartedAt) {
+		t.Fatalf("❌ неверные метаданные завершённого выражения: %+v", expr)
+	}
+	fmt.Printf("✅ метаданные выражения: задач %d из %d, вычисление %d мс, ожидание %d мс\n",
+		expr.TasksDone, expr.TasksTotal, expr.ComputeTimeMs, expr.QueueWaitMs)
+}
@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrDraining       = errors.New("оркестратор останавливается")
+	ErrStorageTimeout = errors.New("хранилище выражений не отвечает")
+)
+
+// storageTimeout — сколько /readyz ждёт доступа к хранилищу выражений.
+const storageTimeout = time.Second
+
+// Drain переводит оркестратор в режим остановки: /readyz отвечает 503,
+// чтобы балансировщик перестал направлять сюда запросы.
+func (o *Orchestrator) Drain() {
+	o.draining.Store(true)
+}
+
+// Ready возвращает nil, если оркестратор готов принимать запросы, иначе — причину.
+func (o *Orchestrator) Ready() error {
+	if o.draining.Load() {
+		return ErrDraining
+	}
+	if _, ok := o.Leader(); o.clustered() && !ok {
+		return ErrNoLeader
+	}
+	return o.checkStorage()
+}
+
+// checkStorage проверяет, что хранилище выражений отвечает и изменения состояния
+// можно сохранить: журнал лидера открыт и последняя запись в него удалась,
+// а реплика Raft — лидер или получает heartbeat от лидера.
+func (o *Orchestrator) checkStorage() error {
+	if err := o.checkLock(); err != nil {
+		return err
+	}
+	switch {
+	case o.raft != nil:
+		return o.raft.health()
+	case o.wal != nil && o.IsLeader():
+		return o.wal.health()
+	}
+	return nil
+}
+
+// checkLock проверяет, что блокировку хранилища выражений удаётся получить
+// за storageTimeout: иначе оркестратор завис и запросы к нему не пройдут.
+func (o *Orchestrator) checkLock() error {
+	for deadline := time.Now().Add(storageTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if o.mu.TryLock() {
+			o.mu.Unlock()
+			return nil
+		}
+	}
+	return ErrStorageTimeout
+}
+
+// HandleHealthz отвечает 200, пока процесс жив.
+func (o *Orchestrator) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// HandleReadyz отвечает 200, если оркестратор готов принимать запросы, и 503 — если нет.
+func (o *Orchestrator) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := o.Ready(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "not_ready", "reason": fmt.Sprint(err)})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
+
+// registerHealth добавляет /healthz, /readyz и /version. Они доступны без аутентификации.
+func (o *Orchestrator) registerHealth(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", o.HandleHealthz)
+	mux.HandleFunc("/readyz", o.HandleReadyz)
+	mux.HandleFunc("/version", buildinfo.Handler(buildinfo.Get()))
+}
@@ -0,0 +1,110 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+
+	get := func(h http.Handler, path string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]any
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec.Code, body
+	}
+
+	for _, h := range []http.Handler{o.PublicHandler(), o.InternalHandler()} {
+		if code, _ := get(h, "/healthz"); code != http.StatusOK {
+			t.Fatalf("❌ /healthz: ожидали 200, а получили %d", code)
+		}
+		if code, _ := get(h, "/readyz"); code != http.StatusOK {
+			t.Fatalf("❌ /readyz: ожидали 200, а получили %d", code)
+		}
+		if code, body := get(h, "/version"); code != http.StatusOK || body["go_version"] != runtime.Version() {
+			t.Fatalf("❌ /version: неверный ответ %d %v", code, body)
+		}
+	}
+	fmt.Println("✅ /healthz, /readyz и /version доступны на обоих портах")
+
+	o.Drain()
+	code, body := get(o.PublicHandler(), "/readyz")
+	if code != http.StatusServiceUnavailable || body["reason"] != orchestrator.ErrDraining.Error() {
+		t.Fatalf("❌ /readyz при остановке: ожидали 503, а получили %d %v", code, body)
+	}
+	if code, _ := get(o.PublicHandler(), "/healthz"); code != http.StatusOK {
+		t.Fatalf("❌ /healthz при остановке: ожидали 200, а получили %d", code)
+	}
+	fmt.Println("✅ При остановке /readyz отвечает 503")
+}
+
+func TestReadyzWAL(t *testing.T) {
+	o := openWAL(t, orchestrator.WALConfig{Dir: t.TempDir()})
+	if err := o.Ready(); err != nil {
+		t.Fatalf("❌ оркестратор с открытым журналом не готов: %v", err)
+	}
+
+	// Журнал закрыт: изменения некуда записать, запросы к оркестратору не пройдут.
+	o.Close()
+	if err := o.Ready(); !errors.Is(err, orchestrator.ErrWAL) {
+		t.Fatalf("❌ ожидали ошибку журнала, а получили %v", err)
+	}
+	fmt.Println("✅ /readyz отвечает 503, если журнал недоступен")
+}
+
+func TestReadyzRaftPartition(t *testing.T) {
+	ids := []string{"node-1", "node-2", "node-3"}
+	var peers []raft.Server
+	transports := make([]*raft.InmemTransport, len(ids))
+	for i, id := range ids {
+		addr, transport := raft.NewInmemTransport(raft.ServerAddress(id))
+		transports[i] = transport
+		peers = append(peers, raft.Server{ID: raft.ServerID(id), Address: addr})
+	}
+	for _, a := range transports {
+		for _, b := range transports {
+			a.Connect(b.LocalAddr(), b)
+		}
+	}
+
+	replicas := make([]*orchestrator.Orchestrator, len(ids))
+	for i, id := range ids {
+		replicas[i], _ = startRaftReplica(t, id, transports[i], peers, "")
+		t.Cleanup(func() { replicas[i].Close() })
+	}
+	leader := waitLeader(t, replicas)
+	for _, o := range replicas {
+		waitFor(t, "готовность реплики", func() bool { return o.Ready() == nil })
+	}
+	fmt.Println("✅ Все реплики кластера готовы")
+
+	// Ведомая реплика отрезана от кластера: она помнит лидера, но не получает от него записи.
+	i := 0
+	for replicas[i] == leader {
+		i++
+	}
+	transports[i].DisconnectAll()
+	for j, transport := range transports {
+		if j != i {
+			transport.Disconnect(transports[i].LocalAddr())
+		}
+	}
+
+	waitFor(t, "503 от отрезанной реплики", func() bool {
+		return replicas[i].Ready() != nil
+	})
+	if err := leader.Ready(); err != nil {
+		t.Fatalf("❌ лидер с большинством реплик не готов: %v", err)
+	}
+	fmt.Println("✅ Реплика без связи с лидером отвечает на /readyz 503")
+}
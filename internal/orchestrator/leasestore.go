@@ -0,0 +1,174 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Lease — аренда лидерства: кто лидер, где его API и до какого момента аренда действует.
+type Lease struct {
+	Holder      string    `json:"holder"`
+	PublicURL   string    `json:"public_url"`
+	InternalURL string    `json:"internal_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// LeaseStore — общее для всех реплик хранилище аренды лидерства.
+type LeaseStore interface {
+	// TryAcquire захватывает аренду для candidate, если она свободна, истекла
+	// или уже принадлежит candidate.Holder (тогда аренда продлевается).
+	// Возвращает аренду, действующую после вызова, — свою или чужую.
+	TryAcquire(candidate Lease) (Lease, error)
+	// Release освобождает аренду, если она принадлежит holder.
+	Release(holder string) error
+}
+
+// acquire — общее для хранилищ правило захвата аренды.
+func acquire(current, candidate Lease, now time.Time) Lease {
+	if current.Holder == "" || current.Holder == candidate.Holder || !now.Before(current.ExpiresAt) {
+		return candidate
+	}
+	return current
+}
+
+// MemoryLeaseStore хранит аренду в памяти. Подходит для нескольких оркестраторов
+// в одном процессе, например в тестах.
+type MemoryLeaseStore struct {
+	// Now — источник времени. По умолчанию time.Now.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	lease Lease
+}
+
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{Now: time.Now}
+}
+
+func (s *MemoryLeaseStore) TryAcquire(candidate Lease) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lease = acquire(s.lease, candidate, s.Now())
+	return s.lease, nil
+}
+
+func (s *MemoryLeaseStore) Release(holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease.Holder == holder {
+		s.lease = Lease{}
+	}
+	return nil
+}
+
+// staleLockAge — через сколько блокировку файла аренды можно считать брошенной.
+const staleLockAge = 5 * time.Second
+
+var errLockTimeout = errors.New("не удалось заблокировать файл аренды")
+
+// FileLeaseStore хранит аренду в JSON-файле на общем для реплик томе.
+// Изменения защищены файлом блокировки, создаваемым атомарно (O_EXCL).
+type FileLeaseStore struct {
+	path string
+}
+
+func NewFileLeaseStore(path string) *FileLeaseStore {
+	return &FileLeaseStore{path: path}
+}
+
+func (s *FileLeaseStore) TryAcquire(candidate Lease) (Lease, error) {
+	var lease Lease
+	err := s.withLock(func() error {
+		current, err := s.read()
+		if err != nil {
+			return err
+		}
+		lease = acquire(current, candidate, time.Now())
+		if lease == current {
+			return nil
+		}
+		return s.write(lease)
+	})
+	return lease, err
+}
+
+func (s *FileLeaseStore) Release(holder string) error {
+	return s.withLock(func() error {
+		current, err := s.read()
+		if err != nil || current.Holder != holder {
+			return err
+		}
+		return s.write(Lease{})
+	})
+}
+
+func (s *FileLeaseStore) read() (Lease, error) {
+	var lease Lease
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lease, nil
+	}
+	if err != nil {
+		return lease, err
+	}
+	if len(data) == 0 {
+		return lease, nil
+	}
+	if err := json.Unmarshal(data, &lease); err != nil {
+		return lease, fmt.Errorf("ошибка разбора файла аренды: %w", err)
+	}
+	return lease, nil
+}
+
+// write записывает аренду во временный файл и переименовывает его, чтобы
+// читатели никогда не видели файл наполовину записанным.
+func (s *FileLeaseStore) write(lease Lease) error {
+	data, _ := json.Marshal(lease)
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *FileLeaseStore) withLock(fn func() error) error {
+	lockPath := s.path + ".lock"
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			defer os.Remove(lockPath)
+			return fn()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		// Реплика упала, не сняв блокировку.
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return errLockTimeout
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/calculator"
+	"net/http"
+)
+
+// newExpressionLimits читает ограничения выражений из MAX_EXPRESSION_LENGTH,
+// MAX_EXPRESSION_TOKENS, MAX_EXPRESSION_DEPTH и MAX_EXPRESSION_TASKS.
+func newExpressionLimits() calculator.Limits {
+	return calculator.Limits{
+		MaxLength: getEnvInt("MAX_EXPRESSION_LENGTH", 10_000),
+		MaxTokens: getEnvInt("MAX_EXPRESSION_TOKENS", 5_000),
+		MaxDepth:  getEnvInt("MAX_EXPRESSION_DEPTH", 100),
+		MaxTasks:  getEnvInt("MAX_EXPRESSION_TASKS", 1_000),
+	}
+}
+
+// limitBody ограничивает размер тела запроса значением MAX_REQUEST_BODY_BYTES.
+func (o *Orchestrator) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, o.maxBodySize)
+		next.ServeHTTP(w, r)
+	})
+}
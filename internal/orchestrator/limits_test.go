@@ -0,0 +1,44 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpressionLimits(t *testing.T) {
+	t.Setenv("MAX_EXPRESSION_LENGTH", "50")
+	t.Setenv("MAX_EXPRESSION_DEPTH", "2")
+	t.Setenv("MAX_REQUEST_BODY_BYTES", "200")
+	o := orchestrator.NewOrchestrator()
+	h := o.PublicHandler()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Допустимое выражение", `{"expression": "(1+2)*3"}`, http.StatusCreated},
+		{"Слишком длинное выражение", fmt.Sprintf(`{"expression": "%s1"}`, strings.Repeat("1+", 30)), http.StatusRequestEntityTooLarge},
+		{"Слишком глубокая вложенность", `{"expression": "(((1+2)))"}`, http.StatusUnprocessableEntity},
+		{"Слишком большое тело запроса", fmt.Sprintf(`{"expression": "1+1", "callback_url": "%s"}`, strings.Repeat("x", 300)), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("❌ %s: ожидали код %d, а получили %d: %s", tt.name, tt.wantStatus, rec.Code, rec.Body)
+		}
+		fmt.Printf("✅ %s: код %d\n", tt.name, rec.Code)
+	}
+
+	if got := len(o.GetAllExpressions()); got != 1 {
+		t.Fatalf("❌ Отклонённые выражения не должны сохраняться, выражений: %d", got)
+	}
+}
@@ -0,0 +1,207 @@
+package orchestrator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 1000
+)
+
+var (
+	ErrInvalidCursor = errors.New("некорректный cursor")
+	ErrInvalidFilter = errors.New("некорректный фильтр")
+)
+
+// ExpressionFilter — параметры выборки списка выражений.
+type ExpressionFilter struct {
+	// Owner — вернуть только выражения этого владельца.
+	Owner         string
+	Status        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Sort — поле сортировки: id или created_at, с префиксом "-" — по убыванию.
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// ExpressionPage — страница списка выражений.
+type ExpressionPage struct {
+	Expressions []*Expression `json:"expressions"`
+	NextCursor  string        `json:"next_cursor,omitempty"`
+}
+
+// ListExpressions возвращает выражения, подходящие под фильтр, в стабильном порядке.
+// Курсор указывает на последнее выражение предыдущей страницы.
+func (o *Orchestrator) ListExpressions(filter ExpressionFilter) (ExpressionPage, error) {
+	less, err := sortFunc(filter.Sort)
+	if err != nil {
+		return ExpressionPage{}, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	var after *Expression
+	if filter.Cursor != "" {
+		after, err = decodeCursor(filter.Cursor)
+		if err != nil {
+			return ExpressionPage{}, err
+		}
+	}
+
+	o.mu.Lock()
+	matched := make([]*Expression, 0, len(o.expressions))
+	for _, expr := range o.expressions {
+		if filter.Owner != "" && expr.Owner != filter.Owner {
+			continue
+		}
+		if filter.Status != "" && expr.Status != filter.Status {
+			continue
+		}
+		if !filter.CreatedAfter.IsZero() && !expr.CreatedAt.After(filter.CreatedAfter) {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() && !expr.CreatedAt.Before(filter.CreatedBefore) {
+			continue
+		}
+		if after != nil && less(after, expr) >= 0 {
+			continue
+		}
+		copied := *expr
+		matched = append(matched, &copied)
+	}
+	o.mu.Unlock()
+
+	slices.SortFunc(matched, less)
+
+	page := ExpressionPage{Expressions: matched}
+	if len(matched) > limit {
+		page.Expressions = matched[:limit]
+		page.NextCursor = encodeCursor(matched[limit-1])
+	}
+	return page, nil
+}
+
+// GetAllExpressions возвращает все выражения, упорядоченные по ID.
+func (o *Orchestrator) GetAllExpressions() []*Expression {
+	page, _ := o.ListExpressions(ExpressionFilter{Limit: MaxListLimit})
+	result := page.Expressions
+
+	for page.NextCursor != "" {
+		page, _ = o.ListExpressions(ExpressionFilter{Limit: MaxListLimit, Cursor: page.NextCursor})
+		result = append(result, page.Expressions...)
+	}
+
+	return result
+}
+
+func (o *Orchestrator) HandleGetExpressions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseExpressionFilter(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusBadRequest)
+		return
+	}
+
+	page, err := o.ListExpressions(filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseExpressionFilter(r *http.Request) (ExpressionFilter, error) {
+	q := r.URL.Query()
+	filter := ExpressionFilter{
+		Owner:  ownerFilter(r),
+		Status: q.Get("status"),
+		Sort:   q.Get("sort"),
+		Cursor: q.Get("cursor"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, fmt.Errorf("%w: limit=%s", ErrInvalidFilter, v)
+		}
+		filter.Limit = limit
+	}
+
+	for key, dst := range map[string]*time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if v := q.Get(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%w: %s=%s", ErrInvalidFilter, key, v)
+			}
+			*dst = t
+		}
+	}
+
+	return filter, nil
+}
+
+// sortFunc возвращает функцию сравнения для поля сортировки. При равенстве
+// основного поля выражения упорядочиваются по ID, чтобы порядок был стабильным.
+func sortFunc(sort string) (func(a, b *Expression) int, error) {
+	field, desc := strings.CutPrefix(sort, "-")
+
+	var cmp func(a, b *Expression) int
+	switch field {
+	case "", "id":
+		cmp = func(a, b *Expression) int { return a.ID - b.ID }
+	case "created_at":
+		cmp = func(a, b *Expression) int {
+			if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+				return c
+			}
+			return a.ID - b.ID
+		}
+	default:
+		return nil, fmt.Errorf("%w: sort=%s", ErrInvalidFilter, sort)
+	}
+
+	if desc {
+		return func(a, b *Expression) int { return cmp(b, a) }, nil
+	}
+	return cmp, nil
+}
+
+func encodeCursor(expr *Expression) string {
+	raw := fmt.Sprintf("%d:%d", expr.CreatedAt.UnixNano(), expr.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*Expression, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	nanosStr, idStr, ok := strings.Cut(string(raw), ":")
+	nanos, errNanos := strconv.ParseInt(nanosStr, 10, 64)
+	id, errID := strconv.Atoi(idStr)
+	if !ok || errNanos != nil || errID != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Expression{ID: id, CreatedAt: time.Unix(0, nanos)}, nil
+}
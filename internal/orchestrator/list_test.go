@@ -0,0 +1,100 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestListExpressions(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	for _, expr := range []string{"1+1", "2+2", "3+3", "4+4", "5+5"} {
+		if _, err := o.AddExpression(expr); err != nil {
+			t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+		}
+	}
+
+	// Завершаем первое выражение.
+	task := fetchTask(t, o)
+	submitResult(o, task.ID, task.Arg1+task.Arg2)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantIDs    []int
+		wantCursor bool
+		wantStatus int
+	}{
+		{"Все выражения по порядку", "", []int{1, 2, 3, 4, 5}, false, http.StatusOK},
+		{"Фильтр по статусу", "?status=done", []int{1}, false, http.StatusOK},
+		{"Первая страница", "?limit=2", []int{1, 2}, true, http.StatusOK},
+		{"Сортировка по убыванию", "?sort=-created_at&limit=3", []int{5, 4, 3}, true, http.StatusOK},
+		{"Фильтр по времени", "?created_before=2000-01-01T00:00:00Z", []int{}, false, http.StatusOK},
+		{"Неверный limit", "?limit=abc", nil, false, http.StatusBadRequest},
+		{"Неверная сортировка", "?sort=result", nil, false, http.StatusBadRequest},
+		{"Неверный курсор", "?cursor=!!!", nil, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, status := listPage(o, tt.query)
+			if status != tt.wantStatus {
+				t.Fatalf("❌ %s: ожидали код %d, а получили %d", tt.name, tt.wantStatus, status)
+			}
+			if status != http.StatusOK {
+				fmt.Printf("✅ %s: корректно отклонён запрос\n", tt.name)
+				return
+			}
+
+			if ids := pageIDs(page); !slices.Equal(ids, tt.wantIDs) {
+				t.Fatalf("❌ %s: ожидали выражения %v, а получили %v", tt.name, tt.wantIDs, ids)
+			}
+			if (page.NextCursor != "") != tt.wantCursor {
+				t.Fatalf("❌ %s: неожиданный next_cursor '%s'", tt.name, page.NextCursor)
+			}
+			fmt.Printf("✅ %s: %v\n", tt.name, pageIDs(page))
+		})
+	}
+
+	t.Run("Обход по курсору", func(t *testing.T) {
+		var ids []int
+		query := "?limit=2&sort=-id"
+		for {
+			page, status := listPage(o, query)
+			if status != http.StatusOK {
+				t.Fatalf("❌ ожидали код 200, а получили %d", status)
+			}
+			ids = append(ids, pageIDs(page)...)
+			if page.NextCursor == "" {
+				break
+			}
+			query = "?limit=2&sort=-id&cursor=" + page.NextCursor
+		}
+
+		if want := []int{5, 4, 3, 2, 1}; !slices.Equal(ids, want) {
+			t.Fatalf("❌ ожидали выражения %v, а получили %v", want, ids)
+		}
+		fmt.Printf("✅ обход по курсору: %v\n", ids)
+	})
+}
+
+func listPage(o *orchestrator.Orchestrator, query string) (orchestrator.ExpressionPage, int) {
+	rec := httptest.NewRecorder()
+	o.HandleGetExpressions(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expressions"+query, nil))
+
+	var page orchestrator.ExpressionPage
+	json.NewDecoder(rec.Body).Decode(&page)
+	return page, rec.Code
+}
+
+func pageIDs(page orchestrator.ExpressionPage) []int {
+	ids := []int{}
+	for _, expr := range page.Expressions {
+		ids = append(ids, expr.ID)
+	}
+	return ids
+}
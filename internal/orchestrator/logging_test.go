@@ -0,0 +1,37 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestStructuredLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	o := orchestrator.NewOrchestrator(orchestrator.WithLogger(logger))
+
+	id, _ := o.AddExpression("2+2")
+	o.GetNextTask()
+	o.GetNextTask() // пустой опрос пишется на уровне debug и не должен попасть в лог
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("❌ Запись лога не в JSON: %s", line)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("❌ Ожидали одну запись уровня info, а получили %d: %v", len(records), records)
+	}
+	if records[0]["expression_id"] != float64(id) || records[0]["level"] != "INFO" {
+		t.Fatalf("❌ Неверная запись о добавлении выражения: %v", records[0])
+	}
+	fmt.Printf("✅ Запись лога: %v\n", records[0])
+}
@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// durationBuckets — границы гистограмм времени: задачи могут ждать в очереди
+// и выполняться дольше 10 секунд.
+var durationBuckets = slices.Concat(prometheus.DefBuckets, []float64{30, 60})
+
+// orchestratorMetrics — метрики оркестратора, отдаваемые на /metrics.
+type orchestratorMetrics struct {
+	registry *prometheus.Registry
+
+	expressions      *prometheus.GaugeVec
+	queueDepth       prometheus.Gauge
+	taskQueueWait    *prometheus.HistogramVec
+	taskDuration     *prometheus.HistogramVec
+	leaseExpirations prometheus.Counter
+	httpRequests     *prometheus.CounterVec
+	leader           prometheus.Gauge
+	cacheHits        *prometheus.CounterVec
+}
+
+// newOrchestratorMetrics создаёт метрики в собственном реестре: в одном процессе
+// может работать несколько оркестраторов. Метрики среды выполнения Go и процесса
+// регистрируются там же.
+func newOrchestratorMetrics() *orchestratorMetrics {
+	r := prometheus.NewRegistry()
+	r.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	f := promauto.With(r)
+	return &orchestratorMetrics{
+		registry: r,
+		expressions: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "calc_expressions", Help: "Количество выражений по статусам.",
+		}, []string{"status"}),
+		queueDepth: f.NewGauge(prometheus.GaugeOpts{
+			Name: "calc_task_queue_depth", Help: "Количество задач, ожидающих выдачи агенту.",
+		}),
+		taskQueueWait: f.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "calc_task_queue_wait_seconds", Help: "Время ожидания задачи в очереди до выдачи агенту.", Buckets: durationBuckets,
+		}, []string{"operation"}),
+		taskDuration: f.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "calc_task_duration_seconds", Help: "Время от выдачи задачи агенту до получения результата.", Buckets: durationBuckets,
+		}, []string{"operation"}),
+		leaseExpirations: f.NewCounter(prometheus.CounterOpts{
+			Name: "calc_task_lease_expirations_total", Help: "Задачи, возвращённые в очередь: агент перестал отвечать, перезапустился или не вернул результат до конца аренды.",
+		}),
+		httpRequests: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "calc_http_requests_total", Help: "HTTP-запросы к оркестратору.",
+		}, []string{"handler", "method", "code"}),
+		leader: f.NewGauge(prometheus.GaugeOpts{
+			Name: "calc_leader", Help: "1, если реплика — лидер.",
+		}),
+		cacheHits: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "calc_result_cache_hits_total", Help: "Выражения и задачи, результат которых взят из кэша.",
+		}, []string{"kind"}),
+	}
+}
+
+// HandleMetrics отдаёт метрики в текстовом формате Prometheus.
+func (o *Orchestrator) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	counts := map[string]int{StatusPending: 0, StatusInProgress: 0, StatusDone: 0, StatusError: 0, StatusCancelled: 0}
+
+	o.mu.Lock()
+	for _, expr := range o.expressions {
+		counts[expr.Status]++
+	}
+	queued := len(o.tasks)
+	o.mu.Unlock()
+
+	for status, n := range counts {
+		o.metrics.expressions.WithLabelValues(status).Set(float64(n))
+	}
+	o.metrics.queueDepth.Set(float64(queued))
+	o.metrics.leader.Set(boolToFloat(o.IsLeader()))
+
+	promhttp.HandlerFor(o.metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// statusRecorder запоминает код ответа для счётчика запросов.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush нужен потокам событий (SSE).
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrument считает запросы по шаблону маршрута, методу и коду ответа.
+func (o *Orchestrator) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// ServeMux записывает шаблон маршрута в r.Pattern.
+		handler := r.Pattern
+		if handler == "" {
+			handler = "unmatched"
+		}
+		o.metrics.httpRequests.WithLabelValues(handler, r.Method, strconv.Itoa(rec.status)).Inc()
+	})
+}
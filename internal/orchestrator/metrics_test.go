@@ -0,0 +1,47 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	public := o.PublicHandler()
+	internal := httptest.NewServer(o.InternalHandler())
+	defer internal.Close()
+
+	calculateFrom(public, "10.0.0.1:1000", "2+2")
+	calculateFrom(public, "10.0.0.1:1000", "3*3")
+	task := fetchTask(t, o)
+	submitResult(o, task.ID, 4)
+
+	resp, err := http.Get(internal.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("❌ Ошибка запроса метрик: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`calc_expressions{status="done"} 1`,
+		`calc_expressions{status="pending"} 1`,
+		`calc_task_queue_depth 1`,
+		`calc_task_queue_wait_seconds_count{operation="+"} 1`,
+		`calc_task_duration_seconds_count{operation="+"} 1`,
+		`calc_task_lease_expirations_total`,
+		`calc_http_requests_total{code="201",handler="/api/v1/calculate",method="POST"} 2`,
+		`go_goroutines`,
+		`process_resident_memory_bytes`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("❌ В метриках нет строки %q:\n%s", want, body)
+		}
+		fmt.Printf("✅ %s\n", want)
+	}
+}
@@ -1,54 +1,340 @@
 package orchestrator
 
 import (
-	models "Calc_2GO/Models"
-	"Calc_2GO/Pkg/calculator"
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/calculator"
+	"Calc_2GO/pkg/logging"
+	"Calc_2GO/pkg/tracing"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Статусы выражения. done, error и cancelled — финальные.
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusError      = "error"
+	StatusCancelled  = "cancelled"
+)
+
+// ErrInvalidExpression — выражение не удалось разобрать.
+var ErrInvalidExpression = errors.New("ошибка при разборе выражения")
+
 type Orchestrator struct {
 	mu          sync.Mutex
 	expressions map[int]*Expression
 	tasks       []models.Task
-	results     map[int]float64
+	taskInfo    map[int]*TaskInfo
+	lastTaskID  int
+	webhooks    *webhookSender
+	events      *eventBroker
+	keys        *keyStore
+	agentTokens map[string]string
+	// configErr — ошибка настройки из переменных среды, с которой сервер не запускается.
+	configErr   error
+	limiter     *rateLimiter
+	quotas      quotas
+	cache       *resultCache
+	limits      calculator.Limits
+	maxBodySize int64
+	metrics     *orchestratorMetrics
+	logger      *slog.Logger
+	tracer      trace.Tracer
+	draining    atomic.Bool
+	election    *election
+	raft        *raftNode
+	wal         *wal
+	// peerSecret — общий для реплик секрет, которым подписывается адрес клиента
+	// в запросах, переданных лидеру.
+	peerSecret []byte
+	// quiet — команда применяется без внешних эффектов, см. apply.
+	quiet bool
+
+	agents           map[string]*AgentInfo
+	heartbeatTimeout time.Duration
+	// leaseTimeout — за какое время агент должен вернуть результат задачи.
+	leaseTimeout time.Duration
 }
 
 type Expression struct {
-	ID     int     `json:"id"`
-	Status string  `json:"status"`
-	Result float64 `json:"result"`
+	ID         int     `json:"id"`
+	Expression string  `json:"expression"`
+	Status     string  `json:"status"`
+	Result     float64 `json:"result"`
+	// Owner — владелец API-ключа, с которым выражение было отправлено.
+	Owner string `json:"owner,omitempty"`
+	// Error — причина, по которой выражение завершилось со статусом error.
+	Error string `json:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	TasksTotal int `json:"tasks_total"`
+	TasksDone  int `json:"tasks_done"`
+	// ComputeTimeMs — суммарное время вычисления задач агентами.
+	ComputeTimeMs int64 `json:"compute_time_ms"`
+	// QueueWaitMs — суммарное время, которое задачи провели в очереди до выдачи агенту.
+	QueueWaitMs int64 `json:"queue_wait_ms"`
+	// Cached — результат выражения взят из кэша без вычисления.
+	Cached bool `json:"cached,omitempty"`
+
+	callbackURL string
+	client      string
+	taskIDs     []int
+	computeTime time.Duration
+	queueWait   time.Duration
+	span        trace.Span
+}
+
+// ExpressionOptions — дополнительные параметры выражения, переданные при его создании.
+type ExpressionOptions struct {
+	// CallbackURL — адрес, на который будет отправлен результат после завершения выражения.
+	CallbackURL string
+	// Owner — владелец выражения.
+	Owner string
+	// Client — клиент, к которому применяются квоты. По умолчанию — Owner.
+	Client string
+}
+
+func (opts ExpressionOptions) client() string {
+	if opts.Client == "" {
+		return opts.Owner
+	}
+	return opts.Client
+}
+
+// Option настраивает оркестратор при создании.
+type Option func(*Orchestrator)
+
+// WithLogger задаёт логгер оркестратора. По умолчанию логгер настраивается
+// переменными LOG_LEVEL и LOG_FORMAT и пишет в stderr.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Orchestrator) {
+		o.logger = logger
+	}
 }
 
-func NewOrchestrator() *Orchestrator {
-	return &Orchestrator{
-		expressions: make(map[int]*Expression),
-		tasks:       []models.Task{},
-		results:     make(map[int]float64),
+func NewOrchestrator(opts ...Option) *Orchestrator {
+	o := &Orchestrator{logger: logging.New(os.Stderr), tracer: defaultTracer()}
+	for _, opt := range append([]Option{electionFromEnv()}, opts...) {
+		opt(o)
 	}
+
+	o.expressions = make(map[int]*Expression)
+	o.tasks = []models.Task{}
+	o.taskInfo = make(map[int]*TaskInfo)
+	o.webhooks = newWebhookSender(o.logger)
+	o.events = newEventBroker()
+	var keysErr, tokensErr error
+	o.keys, keysErr = newKeyStore(o.logger)
+	o.agentTokens, tokensErr = loadAgentTokens(o.logger)
+	o.configErr = errors.Join(keysErr, tokensErr)
+	o.limiter = newRateLimiter()
+	o.quotas = newQuotas()
+	o.cache = newResultCacheFromEnv()
+	o.limits = newExpressionLimits()
+	o.maxBodySize = int64(getEnvInt("MAX_REQUEST_BODY_BYTES", 1<<20))
+	o.peerSecret = []byte(os.Getenv("PEER_SECRET"))
+	o.metrics = newOrchestratorMetrics()
+
+	o.agents = make(map[string]*AgentInfo)
+	o.heartbeatTimeout = time.Duration(getEnvInt("AGENT_HEARTBEAT_TIMEOUT_MS", 15_000)) * time.Millisecond
+	o.leaseTimeout = time.Duration(getEnvInt("TASK_LEASE_TIMEOUT_MS", 300_000)) * time.Millisecond
+	return o
 }
 
 func (o *Orchestrator) AddExpression(expr string) (int, error) {
-	o.mu.Lock()
-	defer o.mu.Unlock()
+	return o.AddExpressionWithOptions(expr, ExpressionOptions{})
+}
+
+func (o *Orchestrator) AddExpressionWithOptions(expr string, opts ExpressionOptions) (int, error) {
+	return o.AddExpressionContext(context.Background(), expr, opts)
+}
+
+// AddExpressionContext добавляет выражение. Спан выражения и спаны его задач
+// становятся дочерними для спана из ctx.
+func (o *Orchestrator) AddExpressionContext(ctx context.Context, expr string, opts ExpressionOptions) (int, error) {
+	if opts.CallbackURL != "" {
+		if err := o.webhooks.validate(opts.CallbackURL); err != nil {
+			return 0, err
+		}
+	}
+
+	res := o.propose(command{
+		Type:         cmdAddExpression,
+		At:           time.Now(),
+		Expression:   expr,
+		Options:      opts,
+		TraceContext: tracing.Inject(ctx),
+	})
+	return res.ID, res.Err
+}
+
+// prepareExpression проверяет, что выражение будет принято, и фиксирует в команде
+// ограничения, квоты и найденные в кэше результаты: при повторе журнала выражение
+// получит тот же ID и те же задачи, даже если настройки реплики изменились.
+// Вызывается под o.mu.
+func (o *Orchestrator) prepareExpression(cmd *command) error {
+	cmd.Limits, cmd.Quotas = o.limits, o.quotas
+
+	tasks, err := parseExpression(cmd.Expression, 0, cmd.Limits)
+	if err != nil {
+		o.logger.Warn("выражение отклонено", "expression", cmd.Expression, logging.Err(err))
+		return err
+	}
+
+	key, _ := calculator.Canonicalize(cmd.Expression)
+	if result, ok := o.cache.lookup(key, cmd.At); ok {
+		cmd.CachedResult = &result
+		return nil
+	}
+	cmd.CachedTasks = make(map[int]float64)
+	for i, task := range tasks {
+		if result, ok := o.cache.lookup(calculator.TaskKey(task), cmd.At); ok {
+			cmd.CachedTasks[i] = result
+		}
+	}
+
+	client := cmd.Options.client()
+	if err := o.checkQuotas(client, len(tasks)-len(cmd.CachedTasks), cmd.Quotas); err != nil {
+		o.logger.Warn("выражение отклонено", "expression", cmd.Expression, "client", client, logging.Err(err))
+		return err
+	}
+	return nil
+}
+
+// parseExpression разбивает выражение на задачи. Ошибки разбора, кроме превышения
+// ограничений, оборачиваются в ErrInvalidExpression.
+func parseExpression(expr string, id int, limits calculator.Limits) ([]models.Task, error) {
+	tasks, err := calculator.CalcToTasksWithLimits(id, expr, limits)
+	if err != nil && !errors.Is(err, calculator.ErrLimitExceeded) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExpression, err)
+	}
+	return tasks, err
+}
+
+// addExpression ставит в очередь задачи выражения, подготовленного prepareExpression.
+// Вызывается под o.mu.
+func (o *Orchestrator) addExpression(ctx context.Context, cmd command) (int, error) {
+	now := cmd.At
+	client := cmd.Options.client()
 
 	id := len(o.expressions) + 1
-	expression := &Expression{ID: id, Status: "pending"}
-	o.expressions[id] = expression
+	expression := &Expression{
+		ID:          id,
+		Expression:  cmd.Expression,
+		Status:      StatusPending,
+		Owner:       cmd.Options.Owner,
+		CreatedAt:   now,
+		callbackURL: cmd.Options.CallbackURL,
+		client:      client,
+	}
+
+	tracer := o.spanTracer()
+	ctx, expression.span = tracer.Start(ctx, "expression", trace.WithAttributes(
+		attribute.Int("expression_id", id),
+		attribute.String("expression", cmd.Expression),
+	))
+
+	_, parseSpan := tracer.Start(ctx, "parse")
+	tasks, err := parseExpression(cmd.Expression, id, cmd.Limits)
+	parseSpan.SetAttributes(attribute.Int("tasks", len(tasks)))
+	endSpan(parseSpan, err)
 
-	tasks, err := calculator.CalcToTasks(id, expr)
+	// Выражение, которое не удалось разобрать, не сохраняется: клиент не получает
+	// его ID, а незавершённым оно занимало бы квоту клиента.
 	if err != nil {
-		log.Printf("❌ Ошибка при разборе выражения: %v", err)
-		return 0, fmt.Errorf("ошибка при разборе выражения: %v", err)
+		endSpan(expression.span, err)
+		o.logger.Warn("выражение отклонено", logging.ExpressionID(id), logging.Err(err))
+		return 0, err
+	}
+
+	// Такое же выражение уже вычислялось: результат известен сразу. Задачи
+	// не создаются, но прогресс показывает их как выполненные.
+	if cmd.CachedResult != nil {
+		key, _ := calculator.Canonicalize(cmd.Expression)
+		o.cache.touch(key)
+		o.expressions[id] = expression
+		expression.Result = *cmd.CachedResult
+		expression.Cached = true
+		expression.TasksTotal, expression.TasksDone = len(tasks), len(tasks)
+		o.countCacheHit("expression")
+		o.logger.Info("результат выражения взят из кэша", logging.ExpressionID(id), "expression", cmd.Expression)
+		o.finalize(expression, StatusDone, now)
+		return id, nil
 	}
 
-	o.tasks = append(o.tasks, tasks...)
-	log.Printf("✅ Добавлено выражение: %s", expr)
+	// Задачи, вычисляющие уже известные подвыражения, не ставятся в очередь.
+	cached := cmd.CachedTasks
+	for i := range cached {
+		o.cache.touch(calculator.TaskKey(tasks[i]))
+	}
+
+	// Квоты проверяются повторно: между подготовкой и применением команды
+	// клиент мог добавить другие выражения.
+	if err := o.checkQuotas(client, len(tasks)-len(cached), cmd.Quotas); err != nil {
+		endSpan(expression.span, err)
+		o.logger.Warn("выражение отклонено", logging.ExpressionID(id), "client", client, logging.Err(err))
+		return 0, err
+	}
+	o.expressions[id] = expression
+
+	queued := make([]models.Task, 0, len(tasks))
+	for i := range tasks {
+		o.lastTaskID++
+		tasks[i].ID = o.lastTaskID
+		expression.taskIDs = append(expression.taskIDs, tasks[i].ID)
+		info := newTaskInfo(tasks[i], expression.CreatedAt)
+		o.taskInfo[tasks[i].ID] = info
+
+		if result, ok := cached[i]; ok {
+			info.Status = TaskDone
+			info.Result = &result
+			info.FinishedAt = &now
+			info.Cached = true
+			expression.TasksDone++
+			o.countCacheHit("task")
+			continue
+		}
+		o.startTaskSpan(expression, info, "task.queue")
+		queued = append(queued, tasks[i])
+	}
+
+	expression.TasksTotal = len(tasks)
+	o.logger.Info("добавлено выражение", logging.ExpressionID(id), "expression", cmd.Expression, "tasks", len(tasks), "cached_tasks", len(cached))
+
+	if len(tasks) > 0 && expression.TasksDone == expression.TasksTotal {
+		expression.Result = *o.taskInfo[expression.taskIDs[len(expression.taskIDs)-1]].Result
+		o.finalize(expression, StatusDone, now)
+		return id, nil
+	}
+
+	if op, found := o.unroutableOperation(expression, o.liveOperations()); found {
+		o.failExpression(expression, fmt.Errorf("%w: %s", ErrUnsupportedOperation, op).Error(), now)
+		return id, nil
+	}
+
+	o.tasks = append(o.tasks, queued...)
 	return id, nil
 }
 
@@ -56,42 +342,84 @@ func (o *Orchestrator) GetExpression(id int) (*Expression, bool) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	expr, exists := o.expressions[id]
-	return expr, exists
+	if !exists {
+		return nil, false
+	}
+	copied := *expr
+	return &copied, true
 }
 
-func (o *Orchestrator) GetAllExpressions() []*Expression {
-	result := make([]*Expression, 0, len(o.expressions))
+func (o *Orchestrator) GetNextTask() (*models.Task, bool) {
+	return o.nextTask("")
+}
 
-	for _, expr := range o.expressions {
-		result = append(result, expr)
+// nextTask выдаёт агенту первую задачу из очереди, операцию которой он умеет выполнять.
+func (o *Orchestrator) nextTask(agent string) (*models.Task, bool) {
+	tasks := o.NextTasks(agent, 1)
+	if len(tasks) == 0 {
+		return nil, false
 	}
-
-	return result
+	return &tasks[0], true
 }
 
-func (o *Orchestrator) GetNextTask() (*models.Task, bool) {
+// hasTask сообщает, есть ли в очереди задача, которую агент может выполнить.
+// Пустые опросы агентов не доходят до журнала команд.
+func (o *Orchestrator) hasTask(agent string) bool {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
-	if len(o.tasks) == 0 {
-		log.Println("❌ Нет задач, готовых к выполнению")
+	if !slices.ContainsFunc(o.tasks, func(t models.Task) bool { return o.canExecute(agent, t.Operation) }) {
+		o.logger.Debug("нет задач, готовых к выполнению", logging.AgentID(agent))
+		return false
+	}
+	return true
+}
+
+// takeTask извлекает задачу из очереди и отмечает её выданной агенту до leaseUntil.
+// Нулевой leaseUntil — аренда без срока. Вызывается под o.mu.
+func (o *Orchestrator) takeTask(agent string, now, leaseUntil time.Time) (*models.Task, bool) {
+	i := slices.IndexFunc(o.tasks, func(t models.Task) bool { return o.canExecute(agent, t.Operation) })
+	if i < 0 {
 		return nil, false
 	}
 
-	task := o.tasks[0]
-	o.tasks = o.tasks[1:]
-	log.Printf("✅ Задача id %d передана агенту. Выражение: %v", task.ID, task)
-	return &task, true
-}
+	task := o.tasks[i]
+	o.tasks = slices.Delete(o.tasks, i, i+1)
 
-func (o *Orchestrator) HandleGetExpressions(w http.ResponseWriter, r *http.Request) {
-	expressions := o.GetAllExpressions()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(expressions)
+	info := o.taskInfo[task.ID]
+	expr := o.expressions[task.ExpressionID]
+
+	expr.queueWait += now.Sub(info.QueuedAt)
+	if !o.quiet {
+		o.metrics.taskQueueWait.WithLabelValues(task.Operation).Observe(now.Sub(info.QueuedAt).Seconds())
+	}
+	expr.QueueWaitMs = expr.queueWait.Milliseconds()
+	if expr.StartedAt == nil {
+		expr.StartedAt = &now
+	}
+	if expr.Status == StatusPending {
+		expr.Status = StatusInProgress
+		o.events.publish(newEvent(EventStatus, expr))
+	}
+
+	info.Status = TaskInProgress
+	info.Agent = agent
+	info.Attempts++
+	info.StartedAt = &now
+	info.LeaseDeadline = nil
+	if !leaseUntil.IsZero() {
+		info.LeaseDeadline = &leaseUntil
+	}
+
+	endSpan(info.span, nil)
+	ctx := o.startTaskSpan(expr, info, "task.lease", attribute.String("agent_id", agent), attribute.Int("attempt", info.Attempts))
+	task.TraceContext = tracing.Inject(ctx)
+	o.logger.Debug("задача передана агенту", logging.TaskID(task.ID), logging.ExpressionID(task.ExpressionID), logging.AgentID(agent), logging.Operation(task.Operation))
+	return &task, true
 }
 
 func (o *Orchestrator) HandleGetExpressionByID(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/expressions/")
+	idStr, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/expressions/"), "/")
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -100,26 +428,87 @@ func (o *Orchestrator) HandleGetExpressionByID(w http.ResponseWriter, r *http.Re
 	}
 
 	expr, exists := o.GetExpression(id)
-	if !exists {
+	if !exists || !canAccess(r, expr) {
 		http.Error(w, "❌ Выражение не найдено", http.StatusNotFound)
 		return
 	}
 
+	switch sub {
+	case "":
+	case "tasks":
+		o.HandleGetExpressionTasks(w, r, id)
+		return
+	case "events":
+		o.HandleExpressionEvents(w, r, id)
+		return
+	case "cancel":
+		o.HandleCancelExpression(w, r, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(expr)
 }
 
 func (o *Orchestrator) HandleCalculate(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		Expression string `json:"expression"`
+		Expression  string `json:"expression"`
+		CallbackURL string `json:"callback_url"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("❌ Тело запроса больше %d байт", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, fmt.Sprintf("❌ Ошибка при чтении данных: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	id, err := o.AddExpression(request.Expression)
+	opts := ExpressionOptions{CallbackURL: request.CallbackURL, Client: clientKey(r)}
+	if p, ok := principalFrom(r); ok {
+		opts.Owner = p.Name
+	}
+
+	// Клиент может передать свой traceparent, тогда выражение станет частью его трассировки.
+	ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := o.tracer.Start(ctx, "POST /api/v1/calculate", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	id, err := o.AddExpressionContext(ctx, request.Expression, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if errors.Is(err, ErrInvalidCallbackURL) || errors.Is(err, ErrCallbacksDisabled) {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusBadRequest)
+		return
+	}
+	if stateUnavailable(err) {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, ErrQuotaExceeded) {
+		tooManyRequests(w, err, o.quotas.retryAfter)
+		return
+	}
+	var limitErr *calculator.LimitError
+	if errors.As(err, &limitErr) {
+		status := http.StatusUnprocessableEntity
+		if limitErr.Limit == calculator.LimitLength {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, fmt.Sprintf("❌ %v", err), status)
+		return
+	}
+	if errors.Is(err, ErrInvalidExpression) {
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("❌ Ошибка при добавлении выражения: %v", err), http.StatusInternalServerError)
 		return
@@ -141,61 +530,249 @@ func (o *Orchestrator) HandleTask(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	task, exists := o.GetNextTask()
+	if r.URL.Query().Has("limit") {
+		o.handleTaskBatch(w, r)
+		return
+	}
+
+	task, exists := o.nextTask(agentName(r))
 	if !exists {
 		http.Error(w, "❌ Нет доступных задач", http.StatusNotFound)
 		return
 	}
 
-	o.expressions[task.ID].Status = "in_progress"
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 }
 
 func (o *Orchestrator) HandleTaskResult(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		ID     int     `json:"id"`
-		Result float64 `json:"result"`
+		models.TaskResult
+		Results []models.TaskResult `json:"results"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		log.Printf("❌ Ошибка при чтении данных: %v", err)
+		o.logger.Warn("ошибка при чтении результата задачи", logging.Err(err))
 		http.Error(w, fmt.Sprintf("❌ Ошибка при чтении данных: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	o.mu.Lock()
-	defer o.mu.Unlock()
+	agent := r.Header.Get(models.AgentIDHeader)
+	if request.Results != nil {
+		// Пачку, которую не удалось записать целиком, агент должен прислать повторно
+		errs, err := o.recordResults(agent, request.Results)
+		if stateUnavailable(err) {
+			http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]models.Ack{"acks": newAcks(request.Results, errs, err)})
+		return
+	}
 
-	o.results[request.ID] = request.Result
-	log.Printf("✅ Результат задачи %d записан: %f", request.ID, request.Result)
+	errs, err := o.recordResults(agent, []models.TaskResult{request.TaskResult})
+	if err == nil {
+		err = errs[0]
+	}
 
-	isTaskExist := false
-	for _, task := range o.tasks {
-		if task.ID == request.ID {
-			isTaskExist = true
-			break
+	switch {
+	case stateUnavailable(err):
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusServiceUnavailable)
+	case errors.Is(err, ErrNotLeaseHolder):
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusForbidden)
+	case errors.Is(err, ErrTaskNotFound):
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusNotFound)
+	case errors.Is(err, ErrTaskNotInProgress):
+		http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// recordResult записывает результат задачи и завершает выражение,
+// если это была его последняя задача. Если агент сообщил об ошибке выполнения,
+// выражение завершается со статусом error. Если агент представился, результат
+// принимается только от агента, которому задача выдана. Вызывается под o.mu.
+func (o *Orchestrator) recordResult(agent string, res models.TaskResult, now time.Time) error {
+	info, exists := o.taskInfo[res.ID]
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrTaskNotFound, res.ID)
+	}
+	if info.Status != TaskInProgress {
+		return fmt.Errorf("%w: %d", ErrTaskNotInProgress, res.ID)
+	}
+	if agent != "" && info.Agent != agent {
+		return fmt.Errorf("%w: %d", ErrNotLeaseHolder, res.ID)
+	}
+
+	if !o.quiet {
+		o.metrics.taskDuration.WithLabelValues(info.Operation).Observe(now.Sub(*info.StartedAt).Seconds())
+	}
+
+	if res.Error != "" {
+		info.Status = TaskFailed
+		info.Error = res.Error
+		info.FinishedAt = &now
+		endSpan(info.span, errors.New(res.Error))
+		o.failExpression(o.expressions[info.ExpressionID], fmt.Sprintf("задача %d: %s", res.ID, res.Error), now)
+		return nil
+	}
+
+	endSpan(info.span, nil)
+
+	result := res.Result
+	info.Status = TaskDone
+	info.Result = &result
+	info.FinishedAt = &now
+	o.cache.put(calculator.TaskKey(info.task), result, now)
+	o.logger.Debug("результат задачи записан", logging.TaskID(res.ID), logging.ExpressionID(info.ExpressionID), logging.AgentID(info.Agent), logging.Operation(info.Operation), logging.Duration(res.ComputeTime), "result", res.Result)
+
+	expr := o.expressions[info.ExpressionID]
+	expr.TasksDone++
+	expr.computeTime += res.ComputeTime
+	expr.ComputeTimeMs = expr.computeTime.Milliseconds()
+	o.events.publish(newEvent(EventProgress, expr))
+
+	// Последняя задача выражения вычисляет его итоговое значение.
+	if expr.TasksDone == expr.TasksTotal {
+		expr.Result = *o.taskInfo[expr.taskIDs[len(expr.taskIDs)-1]].Result
+		if key, err := calculator.Canonicalize(expr.Expression); err == nil {
+			o.cache.put(key, expr.Result, now)
 		}
+		o.finalize(expr, StatusDone, now)
 	}
+	return nil
+}
+
+func isFinalStatus(status string) bool {
+	return status == StatusDone || status == StatusError || status == StatusCancelled
+}
+
+// finalize переводит выражение в финальный статус и, если задан callback_url,
+// ставит в очередь отправку результата. Вызывается под o.mu.
+func (o *Orchestrator) finalize(expr *Expression, status string, now time.Time) {
+	expr.Status = status
+	expr.FinishedAt = &now
 
-	if !isTaskExist {
-		o.expressions[request.ID].Result = o.results[request.ID]
-		o.expressions[request.ID].Status = "done"
+	expr.span.SetAttributes(attribute.String("status", status))
+	var spanErr error
+	if status == StatusError {
+		spanErr = errors.New(expr.Error)
 	}
+	endSpan(expr.span, spanErr)
+
+	o.events.publish(newEvent(EventResult, expr))
+	if expr.callbackURL != "" && !o.quiet {
+		o.webhooks.enqueue(expr.callbackURL, *expr)
+	}
+}
+
+// PublicHandler возвращает маршрутизатор публичного API оркестратора.
+func (o *Orchestrator) PublicHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/calculate", o.authenticate(o.rateLimit(o.HandleCalculate)))
+	mux.HandleFunc("/api/v1/expressions", o.authenticate(o.HandleGetExpressions))
+	mux.HandleFunc("/api/v1/expressions/", o.authenticate(o.HandleGetExpressionByID))
+	mux.HandleFunc("/api/v1/events", o.authenticate(o.HandleEvents))
+	mux.HandleFunc("/api/v1/callbacks/dead-letters", o.requireAdmin(o.HandleGetDeadLetters))
+	mux.HandleFunc("/api/v1/agents", o.authenticate(o.HandleGetAgents))
+	mux.HandleFunc("/api/v1/admin/keys", o.requireAdmin(o.HandleAdminKeys))
+	mux.HandleFunc("/api/v1/admin/keys/", o.requireAdmin(o.HandleAdminKeys))
+	o.registerHealth(mux)
+
+	return o.instrument(o.leaderProxy(o.limitBody(mux), false))
+}
+
+// InternalHandler возвращает маршрутизатор внутреннего API для агентов.
+func (o *Orchestrator) InternalHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/internal/task", o.authenticateAgent(o.HandleTask))
+	mux.HandleFunc("/internal/agents", o.authenticateAgent(o.HandleAgents))
+	mux.HandleFunc("/internal/agents/", o.authenticateAgent(o.HandleAgents))
+	mux.HandleFunc("/metrics", o.HandleMetrics)
+	o.registerHealth(mux)
 
-	w.WriteHeader(http.StatusOK)
+	return o.instrument(o.leaderProxy(o.limitBody(mux), true))
 }
 
-func (o *Orchestrator) StartServer() {
-	fmt.Println("🚀 Оркестратор запущен на порту 8080")
+// StartServer запускает публичный API (PUBLIC_ADDR, по умолчанию :8080)
+// и внутренний API для агентов (INTERNAL_ADDR, по умолчанию :8081).
+// TLS настраивается переменными TLS_* и INTERNAL_TLS_* соответственно.
+// По SIGTERM или SIGINT оркестратор перестаёт быть готовым (/readyz), ждёт
+// DRAIN_DELAY_MS (по умолчанию 5000), чтобы балансировщик это заметил, и останавливает серверы.
+// Возвращает ошибку, если сервер не удалось запустить или он остановился не по сигналу.
+func (o *Orchestrator) StartServer() error {
+	if o.configErr != nil {
+		return fmt.Errorf("ошибка конфигурации: %w", o.configErr)
+	}
+	public, err := o.newServer(getEnvString("PUBLIC_ADDR", ":8080"), "", o.PublicHandler())
+	if err != nil {
+		return fmt.Errorf("ошибка настройки публичного API: %w", err)
+	}
+	internal, err := o.newServer(getEnvString("INTERNAL_ADDR", ":8081"), "INTERNAL_", o.InternalHandler())
+	if err != nil {
+		return fmt.Errorf("ошибка настройки внутреннего API: %w", err)
+	}
+	if err := o.raftFromEnv(); err != nil {
+		return fmt.Errorf("ошибка подключения к Raft-кластеру: %w", err)
+	}
+	if err := o.walFromEnv(); err != nil {
+		return fmt.Errorf("ошибка восстановления из журнала: %w", err)
+	}
+	if o.clustered() && len(o.peerSecret) == 0 {
+		o.logger.Warn("PEER_SECRET не задан: лимиты запросов, переданных репликами, считаются по адресу реплики")
+	}
+	defer o.Close()
 
-	http.HandleFunc("/api/v1/calculate", o.HandleCalculate)
-	http.HandleFunc("/api/v1/expressions", o.HandleGetExpressions)
-	http.HandleFunc("/api/v1/expressions/", o.HandleGetExpressionByID)
-	http.HandleFunc("/internal/task", o.HandleTask)
+	if o.election != nil {
+		go o.runElection()
+	}
+	go o.runAgentReaper()
+
+	errs := make(chan error, 2)
+	go func() { errs <- listen(internal) }()
+	go func() { errs <- listen(public) }()
+
+	o.logger.Info("оркестратор запущен", "public_addr", public.Addr, "internal_addr", internal.Addr)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("ошибка запуска сервера: %w", err)
+	case sig := <-signals:
+		drainDelay := time.Duration(getEnvInt("DRAIN_DELAY_MS", 5_000)) * time.Millisecond
+		o.logger.Info("остановка оркестратора", "signal", sig.String(), "drain_delay", drainDelay)
+		o.Drain()
+		o.Resign()
+		time.Sleep(drainDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, srv := range []*http.Server{public, internal} {
+			if err := srv.Shutdown(ctx); err != nil {
+				o.logger.Warn("сервер остановлен принудительно", "addr", srv.Addr, logging.Err(err))
+				srv.Close()
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Orchestrator) newServer(addr, tlsPrefix string, handler http.Handler) (*http.Server, error) {
+	tlsConfig, err := serverTLSConfig(tlsPrefix, o.logger)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}, nil
+}
 
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		fmt.Println("❌ Ошибка запуска сервера:", err)
+func listen(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
 	}
+	return srv.ListenAndServe()
 }
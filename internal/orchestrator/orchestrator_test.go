@@ -1,10 +1,11 @@
 package orchestrator_test
 
 import (
-	"Calc_distrib/internal/orchestrator"
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io" // Добавлен импорт
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -20,14 +21,14 @@ func TestOrchestrator(t *testing.T) {
 		wantErr    bool
 		errMsg     string
 	}{
-		{"Простое выражение", "2+2", "completed", 4, false, ""},
-		{"Приоритет операций", "2+2*2", "completed", 6, false, ""},
-		{"Скобки", "(2+3)*4", "completed", 20, false, ""},
-		{"Деление на ноль", "10/0", "completed", 0, true, "division by zero"},
-		{"Неизвестная операция", "2^3", "completed", 0, true, "unknown operation"},
-		{"Пустое выражение", "", "pending", 0, true, "invalid expression"},
-		{"Несбалансированные скобки", "(2+3", "pending", 0, true, "mismatched parentheses"},
-		{"Неверный символ", "2 + a", "pending", 0, true, "invalid character: a"},
+		{"Простое выражение", "2+2", orchestrator.StatusDone, 4, false, ""},
+		{"Приоритет операций", "2+2*2", orchestrator.StatusDone, 6, false, ""},
+		{"Скобки", "(2+3)*4", orchestrator.StatusDone, 20, false, ""},
+		{"Деление на ноль", "10/0", "", 0, true, "division by zero"},
+		{"Неизвестная операция", "2^3", "", 0, true, "invalid character"},
+		{"Пустое выражение", "", "", 0, true, "invalid expression"},
+		{"Несбалансированные скобки", "(2+3", "", 0, true, "mismatched parentheses"},
+		{"Неверный символ", "2 + a", "", 0, true, "invalid character: a"},
 	}
 
 	for _, tt := range tests {
@@ -40,7 +41,7 @@ func TestOrchestrator(t *testing.T) {
 				if err == nil {
 					t.Fatalf("❌ %s: ожидалась ошибка, но её нет", tt.name)
 				}
-				if tt.errMsg != "" && err.Error() != tt.errMsg {
+				if !errors.Is(err, orchestrator.ErrInvalidExpression) || !strings.Contains(err.Error(), tt.errMsg) {
 					t.Fatalf("❌ %s: ожидали сообщение об ошибке '%s', а получили '%s'", tt.name, tt.errMsg, err.Error())
 				}
 				fmt.Printf("✅ %s: корректно отловлена ошибка '%s'\n", tt.name, err.Error())
@@ -51,22 +52,23 @@ func TestOrchestrator(t *testing.T) {
 				t.Fatalf("❌ %s: не ожидали ошибку, но получили: %v", tt.name, err)
 			}
 
-			// Получаем задачу
-			task, exists := o.GetNextTask()
-			if !exists {
-				t.Fatalf("❌ %s: задача не найдена", tt.name)
-			}
+			// Выполняем задачи, пока они есть, как это делает агент
+			for {
+				task, exists := o.GetNextTask()
+				if !exists {
+					break
+				}
 
-			// Выполняем задачу
-			result, err := executeTask(task)
-			if err != nil {
-				t.Fatalf("❌ %s: ошибка при выполнении задачи: %v", tt.name, err)
-			}
+				body := map[string]interface{}{"id": task.ID}
+				if result, err := executeTask(task); err != nil {
+					body["error"] = err.Error()
+				} else {
+					body["result"] = result
+				}
 
-			// Отправляем результат
-			o.HandleTaskResult(httptest.NewRecorder(), &http.Request{
-				Body: io.NopCloser(jsonBody(map[string]interface{}{"id": task.ID, "result": result})), // Исправлено
-			})
+				// Отправляем результат
+				o.HandleTaskResult(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/internal/task", jsonBody(body)))
+			}
 
 			// Проверяем статус выражения
 			expr, exists := o.GetExpression(id)
@@ -87,7 +89,7 @@ func TestOrchestrator(t *testing.T) {
 	}
 }
 
-func executeTask(task *orchestrator.Task) (float64, error) {
+func executeTask(task *models.Task) (float64, error) {
 	switch task.Operation {
 	case "+":
 		return task.Arg1 + task.Arg2, nil
@@ -0,0 +1,322 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+)
+
+// applyTimeout — сколько ждать, пока команда будет записана большинством реплик.
+const applyTimeout = 5 * time.Second
+
+// RaftConfig — настройки участия оркестратора в Raft-кластере.
+type RaftConfig struct {
+	// Node — эта реплика. Node.ID служит её идентификатором в Raft.
+	Node Node
+	// Peers — все участники кластера, включая эту реплику. Используются только
+	// при первом запуске, пока в журнале нет конфигурации кластера.
+	Peers []raft.Server
+	// Transport — транспорт Raft между репликами.
+	Transport raft.Transport
+	// Dir — каталог журнала и снимков. Если пуст, они хранятся в памяти.
+	Dir string
+	// SnapshotThreshold — после скольких новых записей журнала делать снимок
+	// состояния и удалять записи, которые он покрывает. По умолчанию 8192.
+	SnapshotThreshold uint64
+	// HeartbeatTimeout — таймаут heartbeat и выборов Raft. По умолчанию 1 секунда.
+	HeartbeatTimeout time.Duration
+	// LogOutput — куда писать журнал библиотеки Raft. По умолчанию stderr.
+	LogOutput io.Writer
+}
+
+// raftNode — участие оркестратора в Raft-кластере. Реализует raft.FSM:
+// команды из журнала применяются к состоянию оркестратора.
+type raftNode struct {
+	o       *Orchestrator
+	node    Node
+	r       *raft.Raft
+	closers []io.Closer
+	// replayUntil — последняя запись журнала на момент запуска. Эффекты этих
+	// записей реплика уже выполнила до перезапуска, поэтому они применяются молча.
+	replayUntil uint64
+	// contactTimeout — сколько ведомая реплика может не получать heartbeat
+	// лидера, оставаясь готовой к запросам.
+	contactTimeout time.Duration
+
+	mu sync.Mutex
+	// leader — адреса API текущего лидера, объявленные им через журнал.
+	leader Lease
+}
+
+// StartRaft подключает оркестратор к Raft-кластеру. Выражения, задачи и реестр
+// агентов реплицируются: кластер из трёх реплик переживает отказ одной из них.
+// Вызывается до начала обслуживания запросов; выбор лидера через аренду (WithElection)
+// при этом не используется.
+func (o *Orchestrator) StartRaft(cfg RaftConfig) error {
+	n := &raftNode{o: o, node: cfg.Node}
+
+	logOutput := cfg.LogOutput
+	if logOutput == nil {
+		logOutput = os.Stderr
+	}
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(cfg.Node.ID)
+	conf.Logger = hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Warn, Output: logOutput})
+	if cfg.SnapshotThreshold > 0 {
+		conf.SnapshotThreshold = cfg.SnapshotThreshold
+		conf.TrailingLogs = cfg.SnapshotThreshold
+	}
+	if cfg.HeartbeatTimeout > 0 {
+		conf.HeartbeatTimeout = cfg.HeartbeatTimeout
+		conf.ElectionTimeout = cfg.HeartbeatTimeout
+		conf.LeaderLeaseTimeout = cfg.HeartbeatTimeout / 2
+	}
+	n.contactTimeout = 2 * conf.HeartbeatTimeout
+
+	var (
+		logs   raft.LogStore
+		stable raft.StableStore
+		snaps  raft.SnapshotStore
+	)
+	if cfg.Dir == "" {
+		store := raft.NewInmemStore()
+		logs, stable, snaps = store, store, raft.NewInmemSnapshotStore()
+	} else {
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			return err
+		}
+		store, err := raftboltdb.NewBoltStore(filepath.Join(cfg.Dir, "raft.db"))
+		if err != nil {
+			return fmt.Errorf("ошибка открытия журнала Raft: %w", err)
+		}
+		n.closers = append(n.closers, store)
+		if snaps, err = raft.NewFileSnapshotStore(cfg.Dir, 2, logOutput); err != nil {
+			store.Close()
+			return fmt.Errorf("ошибка открытия снимков Raft: %w", err)
+		}
+		logs, stable = store, store
+	}
+	if closer, ok := cfg.Transport.(io.Closer); ok {
+		n.closers = append(n.closers, closer)
+	}
+
+	hasState, err := raft.HasExistingState(logs, stable, snaps)
+	if err != nil {
+		n.close()
+		return err
+	}
+
+	if n.replayUntil, err = logs.LastIndex(); err != nil {
+		n.close()
+		return err
+	}
+
+	if n.r, err = raft.NewRaft(conf, n, logs, stable, snaps, cfg.Transport); err != nil {
+		n.close()
+		return fmt.Errorf("ошибка запуска Raft: %w", err)
+	}
+	if !hasState && len(cfg.Peers) > 0 {
+		if err := n.r.BootstrapCluster(raft.Configuration{Servers: cfg.Peers}).Error(); err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+			n.r.Shutdown()
+			n.close()
+			return fmt.Errorf("ошибка инициализации кластера Raft: %w", err)
+		}
+	}
+
+	o.raft = n
+	go n.watchLeadership()
+	return nil
+}
+
+// raftFromEnv подключает оркестратор к Raft-кластеру, если задан RAFT_ADDR —
+// адрес реплики для Raft. Участники кластера перечисляются в RAFT_PEERS
+// в виде id=адрес через запятую, журнал и снимки хранятся в RAFT_DIR
+// (по умолчанию raft), снимок делается каждые RAFT_SNAPSHOT_THRESHOLD записей.
+func (o *Orchestrator) raftFromEnv() error {
+	addr := os.Getenv("RAFT_ADDR")
+	if addr == "" {
+		return nil
+	}
+
+	advertise, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("неверный RAFT_ADDR: %w", err)
+	}
+	transport, err := raft.NewTCPTransport(addr, advertise, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	var peers []raft.Server
+	for _, peer := range strings.Split(os.Getenv("RAFT_PEERS"), ",") {
+		id, peerAddr, ok := strings.Cut(strings.TrimSpace(peer), "=")
+		if !ok {
+			continue
+		}
+		peers = append(peers, raft.Server{ID: raft.ServerID(id), Address: raft.ServerAddress(peerAddr)})
+	}
+
+	node := nodeFromEnv()
+	if os.Getenv("NODE_ID") == "" {
+		// Идентификатор в Raft должен переживать перезапуск.
+		node.ID, _ = os.Hostname()
+	}
+
+	return o.StartRaft(RaftConfig{
+		Node:              node,
+		Peers:             peers,
+		Transport:         transport,
+		Dir:               getEnvString("RAFT_DIR", "raft"),
+		SnapshotThreshold: uint64(getEnvInt("RAFT_SNAPSHOT_THRESHOLD", 8192)),
+	})
+}
+
+func (n *raftNode) shutdown() error {
+	err := n.r.Shutdown().Error()
+	n.close()
+	return err
+}
+
+func (n *raftNode) close() {
+	for _, c := range n.closers {
+		c.Close()
+	}
+}
+
+func (n *raftNode) propose(cmd command) commandResult {
+	cmd.Node = n.node.ID
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return commandResult{Err: err}
+	}
+
+	future := n.r.Apply(data, applyTimeout)
+	if err := future.Error(); err != nil {
+		return commandResult{Err: fmt.Errorf("%w: %v", ErrReplication, err)}
+	}
+	return future.Response().(commandResult)
+}
+
+func (n *raftNode) isLeader() bool {
+	return n.r.State() == raft.Leader
+}
+
+// currentLeader возвращает адреса лидера, если он известен и уже объявил их.
+// health возвращает nil, если реплика может принимать команды: лидер — пока
+// остаётся лидером, ведомая — пока получает heartbeat от лидера.
+func (n *raftNode) health() error {
+	switch state := n.r.State(); state {
+	case raft.Leader:
+		return nil
+	case raft.Follower:
+		last := n.r.LastContact()
+		if time.Since(last) <= n.contactTimeout {
+			return nil
+		}
+		if last.IsZero() {
+			return fmt.Errorf("%w: нет связи с лидером", ErrReplication)
+		}
+		return fmt.Errorf("%w: нет связи с лидером с %s", ErrReplication, last.Format(time.RFC3339))
+	default:
+		return fmt.Errorf("%w: реплика в состоянии %s", ErrReplication, state)
+	}
+}
+
+func (n *raftNode) currentLeader() (Lease, bool) {
+	_, id := n.r.LeaderWithID()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.leader, id != "" && string(id) == n.leader.Holder
+}
+
+func (n *raftNode) setLeader(lease Lease) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.leader = lease
+}
+
+// watchLeadership объявляет адреса реплики через журнал, когда она становится лидером,
+// чтобы остальные реплики знали, куда передавать запросы.
+func (n *raftNode) watchLeadership() {
+	for isLeader := range n.r.LeaderCh() {
+		n.o.metrics.leader.Set(boolToFloat(isLeader))
+		if !isLeader {
+			n.o.logger.Warn("реплика больше не лидер", "node_id", n.node.ID)
+			continue
+		}
+
+		n.o.logger.Info("реплика стала лидером", "node_id", n.node.ID)
+		res := n.propose(command{Type: cmdLeader, At: time.Now(), Leader: &Lease{
+			Holder:      n.node.ID,
+			PublicURL:   n.node.PublicURL,
+			InternalURL: n.node.InternalURL,
+		}})
+		if res.Err != nil {
+			n.o.logger.Warn("ошибка при объявлении лидера", logging.Err(res.Err))
+		}
+	}
+}
+
+// Apply применяет команду из журнала. Внешние эффекты выполняет только реплика,
+// предложившая команду, и только один раз: записи, повторно применяемые после
+// перезапуска, их не выполняют.
+func (n *raftNode) Apply(entry *raft.Log) any {
+	var cmd command
+	if err := json.Unmarshal(entry.Data, &cmd); err != nil {
+		return commandResult{Err: fmt.Errorf("ошибка разбора команды: %w", err)}
+	}
+	if cmd.Type == cmdLeader {
+		n.setLeader(*cmd.Leader)
+		return commandResult{}
+	}
+	return n.o.apply(cmd, cmd.Node != n.node.ID || entry.Index <= n.replayUntil)
+}
+
+func (n *raftNode) Snapshot() (raft.FSMSnapshot, error) {
+	n.mu.Lock()
+	leader := n.leader
+	n.mu.Unlock()
+	return &raftSnapshot{State: n.o.snapshotState(), Leader: leader}, nil
+}
+
+func (n *raftNode) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+
+	var s raftSnapshot
+	if err := json.NewDecoder(snapshot).Decode(&s); err != nil {
+		return fmt.Errorf("ошибка разбора снимка: %w", err)
+	}
+	n.o.restoreState(s.State)
+	n.setLeader(s.Leader)
+	return nil
+}
+
+// raftSnapshot — снимок состояния вместе с адресами лидера: записи журнала,
+// в которых лидер их объявил, удаляются вместе с остальными.
+type raftSnapshot struct {
+	State  stateSnapshot `json:"state"`
+	Leader Lease         `json:"leader"`
+}
+
+func (s *raftSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *raftSnapshot) Release() {}
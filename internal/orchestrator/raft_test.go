@@ -0,0 +1,234 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// startRaftReplica поднимает оркестратор с публичным API и подключает его к кластеру.
+func startRaftReplica(t *testing.T, id string, transport raft.Transport, peers []raft.Server, dir string) (*orchestrator.Orchestrator, *httptest.Server) {
+	t.Helper()
+
+	o := orchestrator.NewOrchestrator()
+	srv := httptest.NewServer(o.PublicHandler())
+	t.Cleanup(srv.Close)
+
+	err := o.StartRaft(orchestrator.RaftConfig{
+		Node:             orchestrator.Node{ID: id, PublicURL: srv.URL},
+		Peers:            peers,
+		Transport:        transport,
+		Dir:              dir,
+		HeartbeatTimeout: 100 * time.Millisecond,
+		LogOutput:        io.Discard,
+	})
+	if err != nil {
+		t.Fatalf("❌ не удалось запустить Raft: %v", err)
+	}
+	return o, srv
+}
+
+// waitFor ждёт выполнения условия не дольше пяти секунд.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("❌ не дождались: %s", what)
+		}
+	}
+}
+
+// waitLeader ждёт, пока одна из реплик станет лидером и объявит свои адреса остальным.
+func waitLeader(t *testing.T, replicas []*orchestrator.Orchestrator) *orchestrator.Orchestrator {
+	t.Helper()
+	var leader *orchestrator.Orchestrator
+	waitFor(t, "выбор лидера", func() bool {
+		leader = nil
+		for _, o := range replicas {
+			if _, ok := o.Leader(); !ok {
+				return false
+			}
+			if o.IsLeader() {
+				leader = o
+			}
+		}
+		return leader != nil
+	})
+	return leader
+}
+
+func TestRaftCluster(t *testing.T) {
+	ids := []string{"node-1", "node-2", "node-3"}
+	var peers []raft.Server
+	transports := make([]*raft.InmemTransport, len(ids))
+	for i, id := range ids {
+		addr, transport := raft.NewInmemTransport(raft.ServerAddress(id))
+		transports[i] = transport
+		peers = append(peers, raft.Server{ID: raft.ServerID(id), Address: addr})
+	}
+	for _, a := range transports {
+		for _, b := range transports {
+			a.Connect(b.LocalAddr(), b)
+		}
+	}
+
+	replicas := make([]*orchestrator.Orchestrator, len(ids))
+	servers := make([]*httptest.Server, len(ids))
+	for i, id := range ids {
+		replicas[i], servers[i] = startRaftReplica(t, id, transports[i], peers, "")
+		t.Cleanup(func() { replicas[i].Close() })
+	}
+
+	leader := waitLeader(t, replicas)
+	fmt.Println("✅ Кластер выбрал лидера")
+
+	// Выражение, отправленное ведомой реплике, попадает к лидеру и реплицируется на все реплики.
+	var follower *httptest.Server
+	for i, o := range replicas {
+		if o != leader {
+			follower = servers[i]
+		}
+	}
+	resp, err := http.Post(follower.URL+"/api/v1/calculate", "application/json", strings.NewReader(`{"expression": "(1+2)*(3+4)"}`))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("❌ ожидали 201 через ведомую реплику, а получили %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	for _, o := range replicas {
+		waitFor(t, "репликация выражения", func() bool {
+			_, exists := o.GetExpression(1)
+			return exists
+		})
+	}
+	fmt.Println("✅ Выражение реплицировано на все реплики")
+
+	tasks := leader.NextTasks("agent-1", 2)
+	if len(tasks) != 2 {
+		t.Fatalf("❌ ожидали 2 задачи, а получили %d", len(tasks))
+	}
+	if acks := leader.RecordResults("agent-1", []models.TaskResult{{ID: tasks[0].ID, Result: 3}}); acks[0].Status != models.AckOK {
+		t.Fatalf("❌ результат не принят: %+v", acks)
+	}
+
+	// Лидер отказал: оставшиеся две реплики выбирают нового и продолжают с того же места.
+	leader.Close()
+	var rest []*orchestrator.Orchestrator
+	for _, o := range replicas {
+		if o != leader {
+			rest = append(rest, o)
+		}
+	}
+	newLeader := waitLeader(t, rest)
+	fmt.Println("✅ После отказа лидера выбран новый")
+
+	if expr, _ := newLeader.GetExpression(1); expr.TasksDone != 1 {
+		t.Fatalf("❌ новый лидер не знает о выполненной задаче: %+v", expr)
+	}
+	if acks := newLeader.RecordResults("agent-1", []models.TaskResult{{ID: tasks[1].ID, Result: 7}}); acks[0].Status != models.AckOK {
+		t.Fatalf("❌ новый лидер не принял результат задачи, выданной прежним: %+v", acks)
+	}
+
+	last := newLeader.NextTasks("agent-1", 10)
+	if len(last) != 1 {
+		t.Fatalf("❌ ожидали последнюю задачу, а получили %d", len(last))
+	}
+	newLeader.RecordResults("agent-1", []models.TaskResult{{ID: last[0].ID, Result: 21}})
+
+	for _, o := range rest {
+		waitFor(t, "завершение выражения на всех репликах", func() bool {
+			expr, _ := o.GetExpression(1)
+			return expr.Status == orchestrator.StatusDone && expr.Result == 21
+		})
+	}
+	fmt.Println("✅ Новый лидер завершил выражение")
+}
+
+func TestRaftSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	peers := []raft.Server{{ID: "node-1", Address: "node-1"}}
+
+	_, transport := raft.NewInmemTransport("node-1")
+	o, _ := startRaftReplica(t, "node-1", transport, peers, dir)
+	waitLeader(t, []*orchestrator.Orchestrator{o})
+
+	for _, expr := range []string{"1+1", "2+2", "3+3"} {
+		if _, err := o.AddExpression(expr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Snapshot(); err != nil {
+		t.Fatalf("❌ не удалось сделать снимок: %v", err)
+	}
+	// Эта запись попадёт в журнал после снимка.
+	o.AddExpression("4+4")
+	o.Close()
+
+	_, transport = raft.NewInmemTransport("node-1")
+	o, _ = startRaftReplica(t, "node-1", transport, peers, dir)
+	defer o.Close()
+	waitLeader(t, []*orchestrator.Orchestrator{o})
+
+	waitFor(t, "восстановление выражений", func() bool { return len(o.GetAllExpressions()) == 4 })
+	if expr, _ := o.GetExpression(4); expr.Expression != "4+4" || expr.TasksTotal != 1 {
+		t.Fatalf("❌ выражение из журнала восстановлено неверно: %+v", expr)
+	}
+	if id, _ := o.AddExpression("5+5"); id != 5 {
+		t.Fatalf("❌ ожидали id 5, а получили %d", id)
+	}
+	if tasks := o.NextTasks("agent-1", 10); len(tasks) != 5 || tasks[4].ID != 5 {
+		t.Fatalf("❌ ожидали 5 задач в очереди, а получили %+v", tasks)
+	}
+	fmt.Println("✅ Состояние восстановлено из снимка и журнала")
+}
+
+func TestRaftRestartNoDuplicateWebhooks(t *testing.T) {
+	t.Setenv("CALLBACK_SECRET", "secret")
+	t.Setenv("CALLBACK_MAX_ATTEMPTS", "1")
+
+	var delivered atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+	}))
+	defer receiver.Close()
+
+	dir := t.TempDir()
+	peers := []raft.Server{{ID: "node-1", Address: "node-1"}}
+
+	_, transport := raft.NewInmemTransport("node-1")
+	o, _ := startRaftReplica(t, "node-1", transport, peers, dir)
+	waitLeader(t, []*orchestrator.Orchestrator{o})
+
+	if _, err := o.AddExpressionWithOptions("2+2", orchestrator.ExpressionOptions{CallbackURL: receiver.URL}); err != nil {
+		t.Fatal(err)
+	}
+	tasks := o.NextTasks("agent-1", 1)
+	o.RecordResults("agent-1", []models.TaskResult{{ID: tasks[0].ID, Result: 4}})
+	waitFor(t, "доставка callback", func() bool { return delivered.Load() == 1 })
+	o.Close()
+
+	// После перезапуска реплика применяет свои записи журнала повторно.
+	_, transport = raft.NewInmemTransport("node-1")
+	o, _ = startRaftReplica(t, "node-1", transport, peers, dir)
+	defer o.Close()
+	waitLeader(t, []*orchestrator.Orchestrator{o})
+
+	waitFor(t, "восстановление выражения", func() bool {
+		expr, _ := o.GetExpression(1)
+		return expr.Status == orchestrator.StatusDone
+	})
+	time.Sleep(200 * time.Millisecond)
+	if n := delivered.Load(); n != 1 {
+		t.Fatalf("❌ ожидали один callback, а получили %d", n)
+	}
+	fmt.Println("✅ После перезапуска callback не отправлен повторно")
+}
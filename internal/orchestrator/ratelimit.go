@@ -0,0 +1,156 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited   = errors.New("превышен лимит запросов")
+	ErrQuotaExceeded = errors.New("превышена квота")
+)
+
+// maxIdleBuckets — сколько корзин хранится, прежде чем удалять заполненные до краёв.
+const maxIdleBuckets = 10_000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter — token bucket для каждого клиента.
+type rateLimiter struct {
+	rate  float64 // токенов в секунду
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// quotas — ограничения на незавершённую работу одного клиента. 0 — без ограничения.
+type quotas struct {
+	MaxPending int `json:"max_pending,omitempty"`
+	MaxTasks   int `json:"max_queued_tasks,omitempty"`
+	retryAfter time.Duration
+}
+
+// newRateLimiter читает RATE_LIMIT_RPS и RATE_LIMIT_BURST (по умолчанию равен RPS).
+// Без RATE_LIMIT_RPS ограничение выключено.
+func newRateLimiter() *rateLimiter {
+	rps := getEnvInt("RATE_LIMIT_RPS", 0)
+	if rps == 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    float64(rps),
+		burst:   float64(getEnvInt("RATE_LIMIT_BURST", rps)),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// newQuotas читает QUOTA_MAX_PENDING_EXPRESSIONS, QUOTA_MAX_QUEUED_TASKS
+// и QUOTA_RETRY_AFTER_S (по умолчанию 5).
+func newQuotas() quotas {
+	return quotas{
+		MaxPending: getEnvInt("QUOTA_MAX_PENDING_EXPRESSIONS", 0),
+		MaxTasks:   getEnvInt("QUOTA_MAX_QUEUED_TASKS", 0),
+		retryAfter: time.Duration(getEnvInt("QUOTA_RETRY_AFTER_S", 5)) * time.Second,
+	}
+}
+
+// allow забирает токен клиента. Если токенов нет, возвращает время до появления следующего.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, exists := l.buckets[client]
+	if !exists {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune удаляет корзины, которые успели заполниться: их клиенты давно не приходили.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// checkQuotas проверяет, что клиент может поставить в очередь ещё newTasks задач
+// в пределах квот q. Вызывается под o.mu.
+func (o *Orchestrator) checkQuotas(client string, newTasks int, q quotas) error {
+	if q.MaxPending == 0 && q.MaxTasks == 0 {
+		return nil
+	}
+
+	pending, tasks := 0, 0
+	for _, expr := range o.expressions {
+		if expr.client != client || isFinalStatus(expr.Status) {
+			continue
+		}
+		pending++
+		tasks += expr.TasksTotal - expr.TasksDone
+	}
+
+	if q.MaxPending > 0 && pending >= q.MaxPending {
+		return fmt.Errorf("%w: незавершённых выражений не больше %d", ErrQuotaExceeded, q.MaxPending)
+	}
+	if q.MaxTasks > 0 && tasks+newTasks > q.MaxTasks {
+		return fmt.Errorf("%w: задач в очереди не больше %d", ErrQuotaExceeded, q.MaxTasks)
+	}
+	return nil
+}
+
+// clientKey — клиент, к которому применяются лимиты: владелец API-ключа или IP-адрес.
+func clientKey(r *http.Request) string {
+	if p, ok := principalFrom(r); ok {
+		return p.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit отклоняет запросы клиента сверх RATE_LIMIT_RPS с кодом 429.
+func (o *Orchestrator) rateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if o.limiter == nil {
+			next(w, r)
+			return
+		}
+
+		if ok, wait := o.limiter.allow(clientKey(r), time.Now()); !ok {
+			tooManyRequests(w, ErrRateLimited, wait)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, err error, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, fmt.Sprintf("❌ %v", err), http.StatusTooManyRequests)
+}
@@ -0,0 +1,106 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func calculateFrom(h http.Handler, addr, expr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(fmt.Sprintf(`{"expression": "%s"}`, expr)))
+	req.RemoteAddr = addr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_RPS", "1")
+	t.Setenv("RATE_LIMIT_BURST", "2")
+	h := orchestrator.NewOrchestrator().PublicHandler()
+
+	tests := []struct {
+		name           string
+		addr           string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{"Первый запрос", "10.0.0.1:1000", http.StatusCreated, ""},
+		{"Второй запрос в пределах burst", "10.0.0.1:1001", http.StatusCreated, ""},
+		{"Третий запрос сверх лимита", "10.0.0.1:1002", http.StatusTooManyRequests, "1"},
+		{"Другой клиент", "10.0.0.2:1000", http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		rec := calculateFrom(h, tt.addr, "1+1")
+		if rec.Code != tt.wantStatus || rec.Header().Get("Retry-After") != tt.wantRetryAfter {
+			t.Fatalf("❌ %s: ожидали код %d и Retry-After %q, а получили %d и %q",
+				tt.name, tt.wantStatus, tt.wantRetryAfter, rec.Code, rec.Header().Get("Retry-After"))
+		}
+		fmt.Printf("✅ %s: код %d\n", tt.name, rec.Code)
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	t.Run("Незавершённые выражения", func(t *testing.T) {
+		t.Setenv("QUOTA_MAX_PENDING_EXPRESSIONS", "2")
+		o := orchestrator.NewOrchestrator()
+		h := o.PublicHandler()
+
+		calculateFrom(h, "10.0.0.1:1000", "1+1")
+		calculateFrom(h, "10.0.0.1:1000", "2+2")
+
+		rec := calculateFrom(h, "10.0.0.1:1000", "3+3")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "5" {
+			t.Fatalf("❌ Ожидали 429 с Retry-After 5, а получили %d и %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if rec := calculateFrom(h, "10.0.0.2:1000", "3+3"); rec.Code != http.StatusCreated {
+			t.Fatalf("❌ Квота другого клиента не должна быть исчерпана, код %d", rec.Code)
+		}
+
+		task := fetchTask(t, o)
+		submitResult(o, task.ID, 2)
+
+		if rec := calculateFrom(h, "10.0.0.1:1000", "3+3"); rec.Code != http.StatusCreated {
+			t.Fatalf("❌ После завершения выражения квота должна освободиться, код %d", rec.Code)
+		}
+		fmt.Println("✅ Квота на незавершённые выражения соблюдается")
+	})
+
+	t.Run("Ошибка разбора", func(t *testing.T) {
+		t.Setenv("QUOTA_MAX_PENDING_EXPRESSIONS", "1")
+		o := orchestrator.NewOrchestrator()
+		h := o.PublicHandler()
+
+		if rec := calculateFrom(h, "10.0.0.1:1000", "2++"); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("❌ Ожидали 422, а получили %d", rec.Code)
+		}
+		if rec := calculateFrom(h, "10.0.0.1:1000", "1+1"); rec.Code != http.StatusCreated {
+			t.Fatalf("❌ Неразобранное выражение не должно занимать квоту, код %d", rec.Code)
+		}
+		if got := len(o.GetAllExpressions()); got != 1 {
+			t.Fatalf("❌ Неразобранное выражение не должно сохраняться, выражений: %d", got)
+		}
+		fmt.Println("✅ Неразобранное выражение не занимает квоту")
+	})
+
+	t.Run("Задачи в очереди", func(t *testing.T) {
+		t.Setenv("QUOTA_MAX_QUEUED_TASKS", "3")
+		o := orchestrator.NewOrchestrator()
+		h := o.PublicHandler()
+
+		if rec := calculateFrom(h, "10.0.0.1:1000", "1+2*3"); rec.Code != http.StatusCreated {
+			t.Fatalf("❌ Ожидали 201, а получили %d", rec.Code)
+		}
+		if rec := calculateFrom(h, "10.0.0.1:1000", "1+2*3"); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("❌ Ожидали 429 при превышении квоты задач, а получили %d", rec.Code)
+		}
+		if got := len(o.GetAllExpressions()); got != 1 {
+			t.Fatalf("❌ Отклонённое выражение не должно сохраняться, выражений: %d", got)
+		}
+		fmt.Println("✅ Квота на задачи в очереди соблюдается")
+	})
+}
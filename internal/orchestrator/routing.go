@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/logging"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrUnsupportedOperation — ни один живой агент не умеет выполнять операцию выражения.
+var ErrUnsupportedOperation = errors.New("нет агентов, поддерживающих операцию")
+
+// canExecute проверяет, может ли агент выполнить операцию. Незарегистрированные
+// агенты считаются поддерживающими все операции. Вызывается под o.mu.
+func (o *Orchestrator) canExecute(agentID, operation string) bool {
+	agent, exists := o.agents[agentID]
+	if !exists {
+		return true
+	}
+	return slices.Contains(agent.Operations, operation)
+}
+
+// liveOperations возвращает операции, которые поддерживает хотя бы один живой агент,
+// или nil, если живых агентов нет. Вызывается под o.mu.
+func (o *Orchestrator) liveOperations() map[string]bool {
+	var ops map[string]bool
+	for _, agent := range o.agents {
+		if agent.Status != AgentAlive {
+			continue
+		}
+		if ops == nil {
+			ops = make(map[string]bool)
+		}
+		for _, op := range agent.Operations {
+			ops[op] = true
+		}
+	}
+	return ops
+}
+
+// unroutableOperation возвращает первую операцию невыполненных задач выражения,
+// которую не поддерживает ни один живой агент. Вызывается под o.mu.
+func (o *Orchestrator) unroutableOperation(expr *Expression, ops map[string]bool) (string, bool) {
+	if ops == nil {
+		return "", false
+	}
+	for _, taskID := range expr.taskIDs {
+		info := o.taskInfo[taskID]
+		if info.Status != TaskDone && !ops[info.Operation] {
+			return info.Operation, true
+		}
+	}
+	return "", false
+}
+
+// failUnroutable завершает с ошибкой выражения, которые не может выполнить
+// ни один живой агент. Пока живых агентов нет, выражения ждут в очереди. Вызывается под o.mu.
+func (o *Orchestrator) failUnroutable(now time.Time) {
+	ops := o.liveOperations()
+	for _, expr := range o.expressions {
+		if isFinalStatus(expr.Status) {
+			continue
+		}
+		if op, found := o.unroutableOperation(expr, ops); found {
+			o.failExpression(expr, fmt.Errorf("%w: %s", ErrUnsupportedOperation, op).Error(), now)
+		}
+	}
+}
+
+// failExpression снимает невыполненные задачи выражения и завершает его с ошибкой. Вызывается под o.mu.
+func (o *Orchestrator) failExpression(expr *Expression, message string, now time.Time) {
+	o.cancelTasks(expr)
+	expr.Error = message
+	o.finalize(expr, StatusError, now)
+	o.logger.Warn("выражение завершено с ошибкой", logging.ExpressionID(expr.ID), "error", message)
+}
+
+// cancelTasks убирает невыполненные задачи выражения из очереди. Вызывается под o.mu.
+func (o *Orchestrator) cancelTasks(expr *Expression) {
+	o.tasks = slices.DeleteFunc(o.tasks, func(t models.Task) bool { return t.ExpressionID == expr.ID })
+	for _, taskID := range expr.taskIDs {
+		if info := o.taskInfo[taskID]; info.Status == TaskQueued || info.Status == TaskInProgress {
+			info.Status = TaskCancelled
+			endSpan(info.span, errTaskCancelled)
+		}
+	}
+}
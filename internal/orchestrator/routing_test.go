@@ -0,0 +1,80 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCapabilityRouting(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	for _, agent := range []orchestrator.AgentInfo{
+		{ID: "adder", Operations: []string{"+", "-"}},
+		{ID: "multiplier", Operations: []string{"*"}},
+	} {
+		if err := o.RegisterAgent(agent); err != nil {
+			t.Fatalf("❌ не ожидали ошибку регистрации: %v", err)
+		}
+	}
+
+	id, _ := o.AddExpression("2*3+1")
+
+	tests := []struct {
+		name          string
+		agent         string
+		wantOperation string
+		wantStatus    int
+	}{
+		{"Агенту сложения — сложение", "adder", "+", http.StatusOK},
+		{"Агенту умножения — умножение", "multiplier", "*", http.StatusOK},
+		{"Подходящих задач нет", "adder", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/internal/task", nil)
+			req.Header.Set(models.AgentIDHeader, tt.agent)
+			rec := httptest.NewRecorder()
+			o.HandleTask(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("❌ %s: ожидали код %d, а получили %d", tt.name, tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				fmt.Printf("✅ %s\n", tt.name)
+				return
+			}
+
+			var task struct {
+				ExpressionID int    `json:"expression_id"`
+				Operation    string `json:"operation"`
+			}
+			json.NewDecoder(rec.Body).Decode(&task)
+			if task.ExpressionID != id || task.Operation != tt.wantOperation {
+				t.Fatalf("❌ %s: ожидали операцию '%s', а получили %+v", tt.name, tt.wantOperation, task)
+			}
+			fmt.Printf("✅ %s: выдана операция %s\n", tt.name, task.Operation)
+		})
+	}
+
+	t.Run("Неподдерживаемая операция", func(t *testing.T) {
+		id, err := o.AddExpression("4/2")
+		if err != nil {
+			t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+		}
+
+		expr, _ := o.GetExpression(id)
+		if expr.Status != orchestrator.StatusError || !strings.Contains(expr.Error, "/") {
+			t.Fatalf("❌ ожидали статус error, а получили %+v", expr)
+		}
+		if task, exists := o.GetNextTask(); exists {
+			t.Fatalf("❌ задача неподдерживаемой операции попала в очередь: %+v", task)
+		}
+		fmt.Printf("✅ выражение завершено с ошибкой: %s\n", expr.Error)
+	})
+}
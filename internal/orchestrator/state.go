@@ -0,0 +1,301 @@
+package orchestrator
+
+import (
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/calculator"
+	"Calc_2GO/pkg/tracing"
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var (
+	// ErrReplication — изменение не удалось применить к реплицированному состоянию.
+	ErrReplication = errors.New("не удалось реплицировать изменение")
+	// ErrWAL — изменение не удалось записать в журнал.
+	ErrWAL = errors.New("не удалось записать изменение в журнал")
+)
+
+// stateUnavailable сообщает, что изменение не применено, потому что состояние
+// сейчас нельзя сохранить: запрос стоит повторить позже.
+func stateUnavailable(err error) bool {
+	return errors.Is(err, ErrReplication) || errors.Is(err, ErrWAL)
+}
+
+// Типы команд.
+const (
+	cmdAddExpression    = "add_expression"
+	cmdCancelExpression = "cancel_expression"
+	cmdTakeTasks        = "take_tasks"
+	cmdRecordResults    = "record_results"
+	cmdRegisterAgent    = "register_agent"
+	cmdHeartbeat        = "heartbeat"
+	cmdReapAgents       = "reap_agents"
+	cmdCreateAPIKey     = "create_api_key"
+	cmdRevokeAPIKey     = "revoke_api_key"
+	cmdLeader           = "leader"
+)
+
+// command — изменение состояния оркестратора. Выражения, задачи, реестр агентов
+// и выпущенные через API ключи меняются только применением команд, поэтому одна
+// и та же последовательность команд приводит любую реплику к одному и тому же
+// состоянию. Время изменения фиксируется в команде, а не берётся при применении.
+type command struct {
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	// Node — реплика, предложившая команду. Только она выполняет внешние эффекты.
+	Node string `json:"node,omitempty"`
+
+	Expression   string            `json:"expression,omitempty"`
+	Options      ExpressionOptions `json:"options"`
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+	ExpressionID int               `json:"expression_id,omitempty"`
+	// Limits, Quotas, CachedResult и CachedTasks — решения, принятые репликой,
+	// предложившей add_expression: ограничения разбора, квоты клиента и найденные
+	// в кэше результаты выражения или его задач (по индексу задачи).
+	Limits       calculator.Limits `json:"limits"`
+	Quotas       quotas            `json:"quotas"`
+	CachedResult *float64          `json:"cached_result,omitempty"`
+	CachedTasks  map[int]float64   `json:"cached_tasks,omitempty"`
+
+	Agent     string              `json:"agent,omitempty"`
+	AgentInfo *AgentInfo          `json:"agent_info,omitempty"`
+	Limit     int                 `json:"limit,omitempty"`
+	Results   []models.TaskResult `json:"results,omitempty"`
+	// LeaseUntil — срок аренды задач, выданных командой take_tasks.
+	LeaseUntil time.Time `json:"lease_until"`
+
+	// KeyHash — хеш выпущенного ключа: сам ключ в журнал не попадает.
+	KeyHash   string     `json:"key_hash,omitempty"`
+	Principal *Principal `json:"principal,omitempty"`
+	KeyID     string     `json:"key_id,omitempty"`
+
+	Leader *Lease `json:"leader,omitempty"`
+}
+
+// commandResult — результат применения команды.
+type commandResult struct {
+	ID    int
+	Tasks []models.Task
+	// Errs — ошибка для каждого результата из command.Results.
+	Errs []error
+	OK   bool
+	Err  error
+}
+
+var noopSpan = trace.SpanFromContext(context.Background())
+
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// propose применяет команду: через Raft, если оркестратор входит в кластер,
+// после записи в журнал, если он включён, или сразу. Команда, которую prepare
+// отклоняет, не применяется и в журнал не попадает.
+func (o *Orchestrator) propose(cmd command) commandResult {
+	if o.raft != nil {
+		if err := o.prepare(&cmd); err != nil {
+			return commandResult{Err: err}
+		}
+		return o.raft.propose(cmd)
+	}
+	if o.wal != nil {
+		return o.wal.propose(cmd)
+	}
+	if err := o.prepare(&cmd); err != nil {
+		return commandResult{Err: err}
+	}
+	return o.apply(cmd, false)
+}
+
+// prepare проверяет, что команда будет применена, и фиксирует в ней решения,
+// зависящие от настроек реплики, чтобы применение команды от них не зависело.
+func (o *Orchestrator) prepare(cmd *command) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch cmd.Type {
+	case cmdAddExpression:
+		return o.prepareExpression(cmd)
+	case cmdCancelExpression:
+		_, err := o.cancellable(cmd.ExpressionID)
+		return err
+	}
+	return nil
+}
+
+// apply применяет команду к состоянию. quiet отключает внешние эффекты —
+// трассировку, метрики и callback-запросы — на репликах, которые лишь повторяют
+// решения другой реплики.
+func (o *Orchestrator) apply(cmd command, quiet bool) commandResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.quiet = quiet
+	defer func() { o.quiet = false }()
+
+	var res commandResult
+	switch cmd.Type {
+	case cmdAddExpression:
+		ctx := tracing.Extract(context.Background(), cmd.TraceContext)
+		res.ID, res.Err = o.addExpression(ctx, cmd)
+	case cmdCancelExpression:
+		res.Err = o.cancelExpression(cmd.ExpressionID, cmd.At)
+	case cmdTakeTasks:
+		res.Tasks = make([]models.Task, 0, cmd.Limit)
+		for len(res.Tasks) < cmd.Limit {
+			task, exists := o.takeTask(cmd.Agent, cmd.At, cmd.LeaseUntil)
+			if !exists {
+				break
+			}
+			res.Tasks = append(res.Tasks, *task)
+		}
+	case cmdRecordResults:
+		res.Errs = make([]error, len(cmd.Results))
+		for i, result := range cmd.Results {
+			res.Errs[i] = o.recordResult(cmd.Agent, result, cmd.At)
+		}
+	case cmdRegisterAgent:
+		o.registerAgent(*cmd.AgentInfo, cmd.At)
+	case cmdHeartbeat:
+		res.OK = o.heartbeat(cmd.Agent, cmd.At)
+	case cmdReapAgents:
+		o.reapAgents(cmd.At)
+	case cmdCreateAPIKey:
+		o.keys.add(cmd.KeyHash, *cmd.Principal)
+	case cmdRevokeAPIKey:
+		res.OK = o.keys.revoke(cmd.KeyID)
+	}
+	return res
+}
+
+// Snapshot делает снимок состояния и удаляет из журнала покрытые им записи.
+func (o *Orchestrator) Snapshot() error {
+	switch {
+	case o.raft != nil:
+		return o.raft.r.Snapshot().Error()
+	case o.wal != nil:
+		return o.wal.snapshot()
+	}
+	return nil
+}
+
+// Close останавливает участие оркестратора в Raft-кластере или закрывает журнал.
+func (o *Orchestrator) Close() error {
+	switch {
+	case o.raft != nil:
+		return o.raft.shutdown()
+	case o.wal != nil:
+		return o.wal.close()
+	}
+	return nil
+}
+
+// spanTracer возвращает трассировщик для спанов выражений и задач. Пока команда
+// применяется без внешних эффектов, спаны не записываются. Вызывается под o.mu.
+func (o *Orchestrator) spanTracer() trace.Tracer {
+	if o.quiet {
+		return noopTracer
+	}
+	return o.tracer
+}
+
+// stateSnapshot — сериализуемое состояние оркестратора.
+type stateSnapshot struct {
+	Expressions []expressionState `json:"expressions"`
+	Queue       []models.Task     `json:"queue"`
+	Tasks       []taskState       `json:"tasks"`
+	LastTaskID  int               `json:"last_task_id"`
+	Agents      []AgentInfo       `json:"agents"`
+	Cache       []cacheEntry      `json:"cache,omitempty"`
+	APIKeys     []apiKeyState     `json:"api_keys,omitempty"`
+	// RevokedKeys — хеши отозванных ключей из API_KEYS_FILE и ADMIN_API_KEY.
+	RevokedKeys []string `json:"revoked_keys,omitempty"`
+}
+
+type expressionState struct {
+	Expression
+	CallbackURL string        `json:"callback_url,omitempty"`
+	Client      string        `json:"client,omitempty"`
+	TaskIDs     []int         `json:"task_ids"`
+	ComputeTime time.Duration `json:"compute_time"`
+	QueueWait   time.Duration `json:"queue_wait"`
+}
+
+type taskState struct {
+	TaskInfo
+	Task models.Task `json:"task"`
+}
+
+// snapshotState копирует состояние оркестратора.
+func (o *Orchestrator) snapshotState() stateSnapshot {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := stateSnapshot{Queue: slices.Clone(o.tasks), LastTaskID: o.lastTaskID, Cache: o.cache.entries()}
+	s.APIKeys, s.RevokedKeys = o.keys.state()
+	for _, expr := range o.expressions {
+		s.Expressions = append(s.Expressions, expressionState{
+			Expression:  *expr,
+			CallbackURL: expr.callbackURL,
+			Client:      expr.client,
+			TaskIDs:     expr.taskIDs,
+			ComputeTime: expr.computeTime,
+			QueueWait:   expr.queueWait,
+		})
+	}
+	for _, info := range o.taskInfo {
+		s.Tasks = append(s.Tasks, taskState{TaskInfo: *info, Task: info.task})
+	}
+	for _, agent := range o.agents {
+		s.Agents = append(s.Agents, *agent)
+	}
+
+	slices.SortFunc(s.Expressions, func(a, b expressionState) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(s.Tasks, func(a, b taskState) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(s.Agents, func(a, b AgentInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return s
+}
+
+// restoreState заменяет состояние оркестратора снимком. Спаны восстановленных
+// выражений и задач не записываются: их трассировки остались у прежнего процесса.
+func (o *Orchestrator) restoreState(s stateSnapshot) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.expressions = make(map[int]*Expression, len(s.Expressions))
+	for _, es := range s.Expressions {
+		expr := es.Expression
+		expr.callbackURL = es.CallbackURL
+		expr.client = es.Client
+		expr.taskIDs = es.TaskIDs
+		expr.computeTime = es.ComputeTime
+		expr.queueWait = es.QueueWait
+		expr.span = noopSpan
+		o.expressions[expr.ID] = &expr
+	}
+
+	o.taskInfo = make(map[int]*TaskInfo, len(s.Tasks))
+	for _, ts := range s.Tasks {
+		info := ts.TaskInfo
+		info.task = ts.Task
+		info.span = noopSpan
+		o.taskInfo[info.ID] = &info
+	}
+
+	o.agents = make(map[string]*AgentInfo, len(s.Agents))
+	for _, agent := range s.Agents {
+		o.agents[agent.ID] = &agent
+	}
+
+	o.tasks = s.Queue
+	if o.tasks == nil {
+		o.tasks = []models.Task{}
+	}
+	o.lastTaskID = s.LastTaskID
+	o.cache.restore(s.Cache)
+	o.keys.restore(s.APIKeys, s.RevokedKeys)
+}
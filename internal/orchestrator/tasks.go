@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	models "Calc_2GO/models"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Статусы задачи.
+const (
+	TaskQueued     = "queued"
+	TaskInProgress = "in_progress"
+	TaskDone       = "done"
+	TaskCancelled  = "cancelled"
+	// TaskFailed — агент не смог выполнить задачу, например из-за деления на ноль.
+	TaskFailed = "failed"
+)
+
+// TaskInfo — состояние задачи выражения: шаг трассы выполнения.
+type TaskInfo struct {
+	ID           int     `json:"id"`
+	ExpressionID int     `json:"expression_id"`
+	Arg1         float64 `json:"arg1"`
+	Arg2         float64 `json:"arg2"`
+	Operation    string  `json:"operation"`
+	Status       string  `json:"status"`
+	// Agent — агент, которому задача выдана последней.
+	Agent    string `json:"agent,omitempty"`
+	Attempts int    `json:"attempts"`
+
+	QueuedAt   time.Time  `json:"queued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// LeaseDeadline — до какого времени агент должен вернуть результат.
+	// Потом задача возвращается в очередь, даже если агент жив.
+	LeaseDeadline *time.Time `json:"lease_deadline,omitempty"`
+
+	Result *float64 `json:"result,omitempty"`
+	// Error — ошибка, с которой агент не смог выполнить задачу.
+	Error string `json:"error,omitempty"`
+	// Cached — результат задачи взят из кэша: такое же подвыражение уже вычислялось.
+	Cached bool `json:"cached,omitempty"`
+
+	task models.Task
+	// span — текущий этап задачи в трассировке: ожидание в очереди или выполнение.
+	span trace.Span
+}
+
+func newTaskInfo(task models.Task, queuedAt time.Time) *TaskInfo {
+	return &TaskInfo{
+		ID:           task.ID,
+		ExpressionID: task.ExpressionID,
+		Arg1:         task.Arg1,
+		Arg2:         task.Arg2,
+		Operation:    task.Operation,
+		Status:       TaskQueued,
+		QueuedAt:     queuedAt,
+		task:         task,
+	}
+}
+
+// leaseExpired сообщает, что задача выдана агенту и её аренда истекла.
+func (info *TaskInfo) leaseExpired(now time.Time) bool {
+	return info.Status == TaskInProgress && info.LeaseDeadline != nil && now.After(*info.LeaseDeadline)
+}
+
+// GetExpressionTasks возвращает задачи выражения в порядке их вычисления.
+func (o *Orchestrator) GetExpressionTasks(id int) ([]TaskInfo, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	expr, exists := o.expressions[id]
+	if !exists {
+		return nil, false
+	}
+
+	tasks := make([]TaskInfo, 0, len(expr.taskIDs))
+	for _, taskID := range expr.taskIDs {
+		tasks = append(tasks, *o.taskInfo[taskID])
+	}
+	return tasks, true
+}
+
+func (o *Orchestrator) HandleGetExpressionTasks(w http.ResponseWriter, r *http.Request, id int) {
+	tasks, exists := o.GetExpressionTasks(id)
+	if !exists {
+		http.Error(w, "❌ Выражение не найдено", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]TaskInfo{"tasks": tasks})
+}
+
+// agentName определяет агента, выполняющего запрос: по заголовку X-Agent-ID,
+// а если его нет — по адресу клиента.
+func agentName(r *http.Request) string {
+	if id := r.Header.Get(models.AgentIDHeader); id != "" {
+		return id
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,89 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpressionTasks(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	id, err := o.AddExpression("(1+2)*(3+4)")
+	if err != nil {
+		t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+	}
+
+	first := fetchTask(t, o)
+	submitResult(o, first.ID, 3)
+	fetchTask(t, o)
+
+	rec := httptest.NewRecorder()
+	o.HandleGetExpressionByID(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/expressions/%d/tasks", id), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("❌ ожидали код 200, а получили %d", rec.Code)
+	}
+
+	var response struct {
+		Tasks []orchestrator.TaskInfo `json:"tasks"`
+	}
+	json.NewDecoder(rec.Body).Decode(&response)
+
+	tests := []struct {
+		operation string
+		status    string
+		attempts  int
+		agent     bool
+	}{
+		{"+", orchestrator.TaskDone, 1, true},
+		{"+", orchestrator.TaskInProgress, 1, true},
+		{"*", orchestrator.TaskQueued, 0, false},
+	}
+
+	if len(response.Tasks) != len(tests) {
+		t.Fatalf("❌ ожидали %d задачи, а получили %d", len(tests), len(response.Tasks))
+	}
+
+	for i, tt := range tests {
+		task := response.Tasks[i]
+		if task.ExpressionID != id || task.Operation != tt.operation || task.Status != tt.status ||
+			task.Attempts != tt.attempts || (task.Agent != "") != tt.agent || (task.StartedAt != nil) != tt.agent {
+			t.Fatalf("❌ задача №%d: неожиданное состояние %+v", i+1, task)
+		}
+		fmt.Printf("✅ задача №%d: %g %s %g — %s\n", i+1, task.Arg1, task.Operation, task.Arg2, task.Status)
+	}
+
+	if response.Tasks[0].Result == nil || *response.Tasks[0].Result != 3 {
+		t.Fatalf("❌ у выполненной задачи нет результата: %+v", response.Tasks[0])
+	}
+}
+
+// fetchTask получает следующую задачу так же, как это делает агент.
+func fetchTask(t *testing.T, o *orchestrator.Orchestrator) models.Task {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/task", nil)
+	req.Header.Set(models.AgentIDHeader, "test-agent")
+	rec := httptest.NewRecorder()
+	o.HandleTask(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("❌ не удалось получить задачу, код ответа: %d", rec.Code)
+	}
+
+	var task models.Task
+	json.NewDecoder(rec.Body).Decode(&task)
+	return task
+}
+
+// submitResult отправляет результат задачи так же, как это делает агент.
+func submitResult(o *orchestrator.Orchestrator, taskID int, result float64) int {
+	req := httptest.NewRequest(http.MethodPost, "/internal/task", strings.NewReader(fmt.Sprintf(`{"id": %d, "result": %g}`, taskID, result)))
+	req.Header.Set(models.AgentIDHeader, "test-agent")
+	rec := httptest.NewRecorder()
+	o.HandleTask(rec, req)
+	return rec.Code
+}
@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/logging"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader отдаёт сертификат сервера и, если включена перезагрузка,
+// перечитывает его с диска после изменения файлов.
+type certReloader struct {
+	certFile, keyFile string
+	reload            bool
+	logger            *slog.Logger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string, reload bool, logger *slog.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, reload: reload, logger: logger}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки сертификата: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = latestModTime(r.certFile, r.keyFile)
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reload && latestModTime(r.certFile, r.keyFile).After(r.modTime) {
+		// Если новый сертификат не читается (например, записан не до конца), продолжаем отдавать старый.
+		if err := r.load(); err != nil {
+			r.logger.Error("ошибка перезагрузки сертификата", "path", r.certFile, logging.Err(err))
+		} else {
+			r.logger.Info("сертификат перезагружен", "path", r.certFile)
+		}
+	}
+	return r.cert, nil
+}
+
+func latestModTime(files ...string) time.Time {
+	var latest time.Time
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// ServerTLSConfig собирает настройки TLS из переменных среды с префиксом prefix:
+// <prefix>TLS_CERT_FILE, <prefix>TLS_KEY_FILE, <prefix>TLS_MIN_VERSION (1.2 или 1.3),
+// <prefix>TLS_RELOAD (перечитывать сертификат при изменении файлов) и
+// <prefix>TLS_CLIENT_CA_FILE (требовать клиентский сертификат, подписанный этим CA).
+// Возвращает nil, если сертификат не задан.
+func ServerTLSConfig(prefix string) (*tls.Config, error) {
+	return serverTLSConfig(prefix, slog.Default())
+}
+
+func serverTLSConfig(prefix string, logger *slog.Logger) (*tls.Config, error) {
+	certFile := os.Getenv(prefix + "TLS_CERT_FILE")
+	keyFile := os.Getenv(prefix + "TLS_KEY_FILE")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	minVersion, err := parseTLSVersion(getEnvString(prefix+"TLS_MIN_VERSION", "1.2"))
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile, os.Getenv(prefix+"TLS_RELOAD") == "true", logger)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if caFile := os.Getenv(prefix + "TLS_CLIENT_CA_FILE"); caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("неподдерживаемая версия TLS: %s", v)
+	}
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("в файле %s нет сертификатов", caFile)
+	}
+	return pool, nil
+}
@@ -0,0 +1,206 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	tls  tls.Certificate
+}
+
+// issueCert выпускает сертификат, подписанный parent, или самоподписанный CA, если parent == nil.
+func issueCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("❌ Ошибка создания сертификата: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, tls: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	keyDER, _ := x509.MarshalECPrivateKey(c.key)
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600)
+	if keyFile != "" {
+		os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	}
+}
+
+// serveTLS запускает handler на локальном порту с заданной конфигурацией TLS.
+func serveTLS(t *testing.T, config *tls.Config, handler http.Handler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("❌ Ошибка запуска listener: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go http.Serve(tls.NewListener(ln, config), handler)
+	return "https://" + ln.Addr().String()
+}
+
+func tlsClient(ca *testCert, clientCert *testCert, maxVersion uint16) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: pool, MaxVersion: maxVersion}
+	if clientCert != nil {
+		config.Certificates = []tls.Certificate{clientCert.tls}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	ca := issueCert(t, "calc-ca", 1, nil)
+	ca.write(t, caFile, "")
+	issueCert(t, "orchestrator", 10, ca).write(t, certFile, keyFile)
+
+	t.Setenv("TLS_CERT_FILE", certFile)
+	t.Setenv("TLS_KEY_FILE", keyFile)
+
+	t.Run("Без сертификата TLS выключен", func(t *testing.T) {
+		config, err := orchestrator.ServerTLSConfig("INTERNAL_")
+		if err != nil || config != nil {
+			t.Fatalf("❌ Ожидали отсутствие настроек TLS, а получили %v, %v", config, err)
+		}
+	})
+
+	t.Run("Перезагрузка сертификата", func(t *testing.T) {
+		t.Setenv("TLS_RELOAD", "true")
+		config, err := orchestrator.ServerTLSConfig("")
+		if err != nil {
+			t.Fatalf("❌ Ошибка настройки TLS: %v", err)
+		}
+		url := serveTLS(t, config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		serial := func() int64 {
+			client := tlsClient(ca, nil, 0)
+			client.Transport.(*http.Transport).DisableKeepAlives = true
+			resp, err := client.Get(url)
+			if err != nil {
+				t.Fatalf("❌ Ошибка запроса: %v", err)
+			}
+			resp.Body.Close()
+			return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+		}
+
+		if got := serial(); got != 10 {
+			t.Fatalf("❌ Ожидали сертификат 10, а получили %d", got)
+		}
+
+		issueCert(t, "orchestrator", 11, ca).write(t, certFile, keyFile)
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(certFile, future, future)
+
+		if got := serial(); got != 11 {
+			t.Fatalf("❌ Ожидали перезагруженный сертификат 11, а получили %d", got)
+		}
+		fmt.Println("✅ Сертификат перезагружен без перезапуска")
+	})
+
+	t.Run("Минимальная версия TLS", func(t *testing.T) {
+		t.Setenv("TLS_MIN_VERSION", "1.0")
+		if _, err := orchestrator.ServerTLSConfig(""); err == nil {
+			t.Fatal("❌ Ожидали ошибку для версии 1.0")
+		}
+
+		t.Setenv("TLS_MIN_VERSION", "1.3")
+		config, err := orchestrator.ServerTLSConfig("")
+		if err != nil {
+			t.Fatalf("❌ Ошибка настройки TLS: %v", err)
+		}
+		url := serveTLS(t, config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		if _, err := tlsClient(ca, nil, tls.VersionTLS12).Get(url); err == nil {
+			t.Fatal("❌ Ожидали отказ клиенту с TLS 1.2")
+		}
+		resp, err := tlsClient(ca, nil, 0).Get(url)
+		if err != nil {
+			t.Fatalf("❌ Ошибка запроса по TLS 1.3: %v", err)
+		}
+		resp.Body.Close()
+		fmt.Println("✅ Клиенты ниже TLS 1.3 отклонены")
+	})
+
+	t.Run("Клиентские сертификаты агентов", func(t *testing.T) {
+		t.Setenv("TLS_CLIENT_CA_FILE", caFile)
+		config, err := orchestrator.ServerTLSConfig("")
+		if err != nil {
+			t.Fatalf("❌ Ошибка настройки TLS: %v", err)
+		}
+
+		o := orchestrator.NewOrchestrator()
+		o.AddExpression("2+2")
+		url := serveTLS(t, config, o.InternalHandler())
+		agentCert := issueCert(t, "agent-1", 20, ca)
+
+		if _, err := tlsClient(ca, nil, 0).Get(url + "/internal/task"); err == nil {
+			t.Fatal("❌ Ожидали отказ клиенту без сертификата")
+		}
+
+		tests := []struct {
+			name       string
+			agent      string
+			wantStatus int
+		}{
+			{"Сертификат другого агента", "agent-2", http.StatusUnauthorized},
+			{"Собственный сертификат", "agent-1", http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			req, _ := http.NewRequest(http.MethodGet, url+"/internal/task", nil)
+			req.Header.Set(models.AgentIDHeader, tt.agent)
+			resp, err := tlsClient(ca, agentCert, 0).Do(req)
+			if err != nil {
+				t.Fatalf("❌ %s: ошибка запроса: %v", tt.name, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("❌ %s: ожидали код %d, а получили %d", tt.name, tt.wantStatus, resp.StatusCode)
+			}
+			fmt.Printf("✅ %s: код %d\n", tt.name, resp.StatusCode)
+		}
+	})
+}
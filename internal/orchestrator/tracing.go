@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "Calc_2GO/internal/orchestrator"
+
+var (
+	errLeaseExpired  = errors.New("агент перестал отвечать, задача возвращена в очередь")
+	errTaskCancelled = errors.New("задача отменена")
+)
+
+// WithTracerProvider задаёт провайдер трассировок. По умолчанию используется
+// глобальный провайдер OpenTelemetry.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *Orchestrator) {
+		o.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// traceContext возвращает контекст со спаном выражения — родителем спанов его задач.
+func (expr *Expression) traceContext() context.Context {
+	return trace.ContextWithSpan(context.Background(), expr.span)
+}
+
+// startTaskSpan начинает очередной этап задачи: ожидание в очереди или выполнение агентом.
+func (o *Orchestrator) startTaskSpan(expr *Expression, info *TaskInfo, name string, attrs ...attribute.KeyValue) context.Context {
+	attrs = append(attrs,
+		attribute.Int("expression_id", expr.ID),
+		attribute.Int("task_id", info.ID),
+		attribute.String("operation", info.Operation),
+	)
+	ctx, span := o.spanTracer().Start(expr.traceContext(), name, trace.WithAttributes(attrs...))
+	info.span = span
+	return ctx
+}
+
+// endSpan завершает спан, отмечая ошибку, если она есть.
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
@@ -0,0 +1,54 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/agent"
+	"Calc_2GO/internal/orchestrator"
+	"fmt"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	o := orchestrator.NewOrchestrator(orchestrator.WithTracerProvider(provider))
+	ag := agent.NewAgent("http://orchestrator.invalid", 1, agent.WithTracerProvider(provider))
+
+	calculateFrom(o.PublicHandler(), "10.0.0.1:1000", "2+2")
+	task := fetchTask(t, o)
+	if task.TraceContext["traceparent"] == "" {
+		t.Fatalf("❌ Задача выдана без контекста трассировки: %+v", task)
+	}
+	result, _ := ag.ExecuteTask(&task)
+	submitResult(o, task.ID, result)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	root := spans["POST /api/v1/calculate"]
+	parents := map[string]string{
+		"expression":   "POST /api/v1/calculate",
+		"parse":        "expression",
+		"task.queue":   "expression",
+		"task.lease":   "expression",
+		"task.execute": "task.lease",
+	}
+	for name, parent := range parents {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("❌ Нет спана %s, есть: %v", name, spans)
+		}
+		if span.SpanContext().TraceID() != root.SpanContext().TraceID() {
+			t.Fatalf("❌ Спан %s из другой трассировки", name)
+		}
+		if span.Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Fatalf("❌ Родитель спана %s — не %s", name, parent)
+		}
+		fmt.Printf("✅ %s → %s\n", parent, name)
+	}
+}
@@ -0,0 +1,419 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/logging"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncPolicy — когда записи журнала сбрасываются на диск.
+type SyncPolicy string
+
+const (
+	// SyncAlways — после каждой записи: изменение не теряется, если запрос получил ответ.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval — раз в WALConfig.SyncInterval: при сбое теряются изменения за последний интервал.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever — на усмотрение операционной системы.
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	walFile      = "wal.log"
+	snapshotFile = "snapshot.json"
+)
+
+// WALConfig — настройки журнала изменений.
+type WALConfig struct {
+	// Dir — каталог журнала и снимка.
+	Dir string
+	// Sync — когда сбрасывать журнал на диск. По умолчанию SyncAlways.
+	Sync SyncPolicy
+	// SyncInterval — период сброса для SyncInterval. По умолчанию 1 секунда.
+	SyncInterval time.Duration
+	// SnapshotEvery — после скольких записей делать снимок состояния и очищать журнал.
+	// По умолчанию 10000.
+	SnapshotEvery int
+}
+
+// walRecord — запись журнала. Seq растёт на единицу с каждой записью
+// и не сбрасывается при очистке журнала.
+type walRecord struct {
+	Seq     uint64  `json:"seq"`
+	Command command `json:"command"`
+}
+
+// walSnapshot — снимок состояния, покрывающий записи журнала до Seq включительно.
+type walSnapshot struct {
+	Seq   uint64        `json:"seq"`
+	State stateSnapshot `json:"state"`
+}
+
+// wal — журнал изменений состояния для оркестратора без Raft. Каждая команда
+// записывается в журнал до применения; при запуске состояние восстанавливается
+// из последнего снимка и записей после него.
+type wal struct {
+	o   *Orchestrator
+	cfg WALConfig
+
+	mu            sync.Mutex
+	file          *os.File
+	seq           uint64
+	sinceSnapshot int
+	dirty         bool
+	// err — ошибка последней записи или сброса журнала на диск; сбрасывается
+	// при следующей успешной записи.
+	err  error
+	stop chan struct{}
+}
+
+// OpenWAL восстанавливает состояние из журнала в cfg.Dir и начинает записывать
+// в него изменения. Выражения в очереди и задачи, выданные агентам до сбоя,
+// продолжают выполняться. Вызывается до начала обслуживания запросов.
+//
+// При выборе лидера (WithElection) журнал должен лежать на общем томе: реплика
+// загружает из него состояние, когда становится лидером, и перестаёт писать
+// в него, когда теряет аренду.
+func (o *Orchestrator) OpenWAL(cfg WALConfig) error {
+	if cfg.Sync == "" {
+		cfg.Sync = SyncAlways
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+	if cfg.SnapshotEvery <= 0 {
+		cfg.SnapshotEvery = 10_000
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return err
+	}
+
+	w := &wal{o: o, cfg: cfg, stop: make(chan struct{})}
+	if o.election == nil {
+		if err := w.load(); err != nil {
+			return err
+		}
+	}
+
+	o.wal = w
+	if cfg.Sync == SyncInterval {
+		go w.runSync()
+	}
+	return nil
+}
+
+// walFromEnv включает журнал, если задан WAL_DIR. Политика сброса на диск —
+// WAL_FSYNC (always, interval или never), период для interval — WAL_FSYNC_INTERVAL_MS,
+// снимок делается каждые WAL_SNAPSHOT_EVERY записей. В Raft-кластере журнал не нужен.
+func (o *Orchestrator) walFromEnv() error {
+	dir := os.Getenv("WAL_DIR")
+	if o.raft != nil {
+		return nil
+	}
+	if dir == "" {
+		if o.election != nil {
+			o.logger.Warn("WAL_DIR не задан: при смене лидера состояние будет потеряно")
+		}
+		return nil
+	}
+
+	policy := SyncPolicy(getEnvString("WAL_FSYNC", string(SyncAlways)))
+	switch policy {
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return fmt.Errorf("неизвестная политика WAL_FSYNC: %q", policy)
+	}
+
+	return o.OpenWAL(WALConfig{
+		Dir:           dir,
+		Sync:          policy,
+		SyncInterval:  time.Duration(getEnvInt("WAL_FSYNC_INTERVAL_MS", 1000)) * time.Millisecond,
+		SnapshotEvery: getEnvInt("WAL_SNAPSHOT_EVERY", 10_000),
+	})
+}
+
+// load заменяет состояние оркестратора состоянием из снимка и журнала
+// и открывает журнал для записи.
+func (w *wal) load() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	w.o.restoreState(stateSnapshot{})
+	w.seq, w.sinceSnapshot, w.dirty, w.err = 0, 0, false, nil
+
+	if err := w.loadSnapshot(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(w.cfg.Dir, walFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	w.file = file
+
+	replayed, err := w.replay()
+	if err != nil {
+		file.Close()
+		w.file = nil
+		return err
+	}
+	w.o.logger.Info("состояние восстановлено из журнала", "dir", w.cfg.Dir, "seq", w.seq, "replayed", replayed)
+	return nil
+}
+
+func (w *wal) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(w.cfg.Dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s walSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("ошибка разбора снимка: %w", err)
+	}
+	w.o.restoreState(s.State)
+	w.seq = s.Seq
+	return nil
+}
+
+// replay применяет записи журнала, не покрытые снимком. Недописанная последняя
+// запись — след сбоя во время записи — отбрасывается.
+func (w *wal) replay() (int, error) {
+	reader := bufio.NewReader(w.file)
+	var offset int64
+	replayed := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return replayed, err
+		}
+
+		var rec walRecord
+		if decodeErr := json.Unmarshal(line, &rec); decodeErr != nil || !bytes.HasSuffix(line, []byte("\n")) {
+			if _, err := reader.Peek(1); err == nil {
+				return replayed, fmt.Errorf("журнал повреждён на смещении %d", offset)
+			}
+			w.o.logger.Warn("отброшена недописанная запись журнала", "offset", offset)
+			if err := w.file.Truncate(offset); err != nil {
+				return replayed, err
+			}
+			break
+		}
+		offset += int64(len(line))
+
+		if rec.Seq <= w.seq {
+			continue
+		}
+		w.o.apply(rec.Command, true)
+		w.seq = rec.Seq
+		w.sinceSnapshot++
+		replayed++
+	}
+
+	_, err := w.file.Seek(offset, io.SeekStart)
+	return replayed, err
+}
+
+// propose проверяет команду, записывает её в журнал и применяет. Отклонённая
+// команда в журнал не попадает. Проверка, запись и применение идут под w.mu,
+// поэтому между ними состояние не меняется.
+func (w *wal) propose(cmd command) commandResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if w.err != nil {
+			return commandResult{Err: fmt.Errorf("%w: %v", ErrWAL, w.err)}
+		}
+		return commandResult{Err: fmt.Errorf("%w: %v", ErrWAL, ErrNoLeader)}
+	}
+	if err := w.o.prepare(&cmd); err != nil {
+		return commandResult{Err: err}
+	}
+	if err := w.append(cmd); err != nil {
+		w.err = err
+		return commandResult{Err: fmt.Errorf("%w: %v", ErrWAL, err)}
+	}
+	res := w.o.apply(cmd, false)
+
+	if w.sinceSnapshot >= w.cfg.SnapshotEvery {
+		if err := w.snapshotLocked(); err != nil {
+			w.o.logger.Warn("ошибка при сохранении снимка", logging.Err(err))
+		}
+	}
+	return res
+}
+
+// append дописывает команду в журнал. Если запись или сброс на диск не удались,
+// журнал обрезается до конца предыдущей записи. Вызывается под w.mu.
+func (w *wal) append(cmd command) error {
+	data, err := json.Marshal(walRecord{Seq: w.seq + 1, Command: cmd})
+	if err != nil {
+		return err
+	}
+	offset, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.file.Write(append(data, '\n'))
+	if err == nil && w.cfg.Sync == SyncAlways {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		w.truncate(offset)
+		return err
+	}
+
+	w.seq++
+	w.sinceSnapshot++
+	w.dirty = true
+	w.err = nil
+	return nil
+}
+
+// truncate отбрасывает неудачную запись: обрывок посреди журнала сделал бы его
+// повреждённым для повтора, а несброшенная запись повторилась бы, хотя команда
+// не применена. Если обрезать не удаётся, журнал закрывается до перезапуска.
+// Вызывается под w.mu.
+func (w *wal) truncate(offset int64) {
+	err := w.file.Truncate(offset)
+	if err == nil {
+		_, err = w.file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		w.o.logger.Error("не удалось обрезать журнал после ошибки записи, журнал закрыт", logging.Err(err))
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+// health возвращает nil, если журнал открыт и последняя запись в него удалась.
+func (w *wal) health() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return fmt.Errorf("%w: %v", ErrWAL, w.err)
+	}
+	if w.file == nil {
+		return fmt.Errorf("%w: журнал закрыт", ErrWAL)
+	}
+	return nil
+}
+
+func (w *wal) snapshot() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.snapshotLocked()
+}
+
+// snapshotLocked сохраняет снимок состояния и очищает журнал. Если процесс упадёт
+// между этими шагами, записи, уже покрытые снимком, будут пропущены по Seq. Вызывается под w.mu.
+func (w *wal) snapshotLocked() error {
+	data, err := json.Marshal(walSnapshot{Seq: w.seq, State: w.o.snapshotState()})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(w.cfg.Dir, snapshotFile)
+	tmp, err := os.CreateTemp(w.cfg.Dir, snapshotFile+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.sinceSnapshot = 0
+	w.o.logger.Debug("сохранён снимок состояния", "seq", w.seq)
+	return nil
+}
+
+// runSync сбрасывает журнал на диск раз в SyncInterval.
+func (w *wal) runSync() {
+	ticker := time.NewTicker(w.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.dirty && w.file != nil {
+				if err := w.file.Sync(); err != nil {
+					w.o.logger.Warn("ошибка при сбросе журнала на диск", logging.Err(err))
+					w.err = err
+				}
+				w.dirty = false
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// release перестаёт писать в журнал. Если snapshot, перед этим сохраняется
+// снимок. Реплика, потерявшая аренду, снимок не сохраняет: журнал уже может
+// принадлежать новому лидеру.
+func (w *wal) release(snapshot bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+
+	var err error
+	if snapshot {
+		err = w.snapshotLocked()
+	}
+	err = errors.Join(err, w.file.Close())
+	w.file = nil
+	return err
+}
+
+// close сохраняет снимок, чтобы следующий запуск не повторял журнал, и закрывает его.
+func (w *wal) close() error {
+	close(w.stop)
+	return w.release(true)
+}
@@ -0,0 +1,171 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openWAL создаёт оркестратор и восстанавливает его состояние из журнала в dir.
+func openWAL(t *testing.T, cfg orchestrator.WALConfig) *orchestrator.Orchestrator {
+	t.Helper()
+	o := orchestrator.NewOrchestrator()
+	if err := o.OpenWAL(cfg); err != nil {
+		t.Fatalf("❌ не удалось открыть журнал: %v", err)
+	}
+	return o
+}
+
+func TestWALRecovery(t *testing.T) {
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir})
+
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-1", Operations: []string{"+", "*"}})
+	o.AddExpression("(1+2)*(3+4)")
+	o.AddExpression("5+5")
+
+	tasks := o.NextTasks("agent-1", 2)
+	o.RecordResults("agent-1", []models.TaskResult{{ID: tasks[0].ID, Result: 3}})
+
+	// Процесс упал, не закрыв журнал.
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir})
+	defer o.Close()
+
+	first, _ := o.GetExpressionTasks(1)
+	if first[0].Status != orchestrator.TaskDone || first[1].Status != orchestrator.TaskInProgress || first[1].Agent != "agent-1" || first[2].Status != orchestrator.TaskQueued {
+		t.Fatalf("❌ задачи восстановлены неверно: %+v", first)
+	}
+	fmt.Println("✅ Выполненные, выданные и ожидающие задачи восстановлены")
+
+	// Агент, получивший задачу до сбоя, больше не отвечает: задача возвращается в очередь.
+	o.ReapAgents(time.Now().Add(time.Hour))
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-2", Operations: []string{"+", "*"}})
+
+	requeued := o.NextTasks("agent-2", 1)
+	if len(requeued) != 1 || requeued[0].ID != tasks[1].ID {
+		t.Fatalf("❌ ожидали повторную выдачу задачи %d, а получили %+v", tasks[1].ID, requeued)
+	}
+	fmt.Println("✅ Задача, выданная до сбоя, выполнена другим агентом")
+
+	if id, _ := o.AddExpression("6+6"); id != 3 {
+		t.Fatalf("❌ ожидали id 3, а получили %d", id)
+	}
+}
+
+func TestWALSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir, SnapshotEvery: 3})
+
+	for i := 1; i <= 5; i++ {
+		o.AddExpression(fmt.Sprintf("%d+%d", i, i))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "snapshot.json")); err != nil {
+		t.Fatalf("❌ снимок не сохранён: %v", err)
+	}
+
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir, SnapshotEvery: 3})
+	if n := len(o.GetAllExpressions()); n != 5 {
+		t.Fatalf("❌ ожидали 5 выражений из снимка и журнала, а получили %d", n)
+	}
+	fmt.Println("✅ Состояние восстановлено из снимка и журнала")
+
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "wal.log")); info.Size() != 0 {
+		t.Fatalf("❌ после закрытия журнал должен быть пуст, а его размер %d", info.Size())
+	}
+
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir})
+	defer o.Close()
+	if n := len(o.GetAllExpressions()); n != 5 {
+		t.Fatalf("❌ ожидали 5 выражений из снимка, а получили %d", n)
+	}
+	fmt.Println("✅ При закрытии состояние сохраняется в снимок")
+}
+
+func TestWALTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir, Sync: orchestrator.SyncNever})
+	o.AddExpression("1+1")
+
+	// Сбой во время записи оставил в конце журнала недописанную запись.
+	f, _ := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_APPEND|os.O_WRONLY, 0o600)
+	f.WriteString(`{"seq": 2, "command": {"type": "add_exp`)
+	f.Close()
+
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir})
+	defer o.Close()
+	if n := len(o.GetAllExpressions()); n != 1 {
+		t.Fatalf("❌ ожидали 1 выражение, а получили %d", n)
+	}
+	if id, err := o.AddExpression("2+2"); err != nil || id != 2 {
+		t.Fatalf("❌ ожидали запись после отброшенной, а получили %d %v", id, err)
+	}
+	fmt.Println("✅ Недописанная запись отброшена")
+}
+
+func TestWALRejectedCommands(t *testing.T) {
+	t.Setenv("QUOTA_MAX_PENDING_EXPRESSIONS", "1")
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir})
+	defer o.Close()
+
+	o.AddExpression("1+1")
+	if _, err := o.AddExpression("2+2"); !errors.Is(err, orchestrator.ErrQuotaExceeded) {
+		t.Fatalf("❌ ожидали превышение квоты, а получили %v", err)
+	}
+	if _, err := o.AddExpression("(2+2"); !errors.Is(err, orchestrator.ErrInvalidExpression) {
+		t.Fatalf("❌ ожидали ошибку разбора, а получили %v", err)
+	}
+	if err := o.CancelExpression(42); !errors.Is(err, orchestrator.ErrExpressionNotFound) {
+		t.Fatalf("❌ ожидали ошибку отмены, а получили %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "wal.log"))
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Fatalf("❌ ожидали 1 запись в журнале, а получили %d:\n%s", n, data)
+	}
+	fmt.Println("✅ Отклонённые команды не записаны в журнал")
+}
+
+func TestWALReplayIgnoresConfig(t *testing.T) {
+	dir := t.TempDir()
+	o := openWAL(t, orchestrator.WALConfig{Dir: dir})
+
+	o.RegisterAgent(orchestrator.AgentInfo{ID: "agent-1", Operations: []string{"+"}})
+	o.AddExpression("1+1")
+	tasks := o.NextTasks("agent-1", 1)
+	o.RecordResults("agent-1", []models.TaskResult{{ID: tasks[0].ID, Result: 2}})
+
+	// Второе выражение берётся из кэша, задачи третьего и четвёртого ставятся в очередь.
+	o.AddExpression("1+1")
+	o.AddExpression("3+3")
+	o.AddExpression("4+4")
+	before, _ := o.GetExpressionTasks(4)
+
+	// Процесс упал и запущен с выключенным кэшем и более строгими квотами и ограничениями.
+	t.Setenv("RESULT_CACHE_SIZE", "0")
+	t.Setenv("QUOTA_MAX_PENDING_EXPRESSIONS", "1")
+	t.Setenv("MAX_EXPRESSION_LENGTH", "2")
+	o = openWAL(t, orchestrator.WALConfig{Dir: dir})
+	defer o.Close()
+
+	if n := len(o.GetAllExpressions()); n != 4 {
+		t.Fatalf("❌ ожидали 4 выражения, а получили %d", n)
+	}
+	if expr, _ := o.GetExpression(2); expr.Status != orchestrator.StatusDone || !expr.Cached {
+		t.Fatalf("❌ выражение из кэша восстановлено неверно: %+v", expr)
+	}
+	after, _ := o.GetExpressionTasks(4)
+	if len(after) != 1 || after[0].ID != before[0].ID {
+		t.Fatalf("❌ задачи восстановлены с другими ID: было %+v, стало %+v", before, after)
+	}
+	fmt.Println("✅ Журнал повторяется одинаково при изменённых настройках")
+}
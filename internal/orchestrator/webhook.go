@@ -0,0 +1,214 @@
+package orchestrator
+
+import (
+	"Calc_2GO/pkg/logging"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SignatureHeader — заголовок с HMAC-SHA256 подписью тела callback-запроса.
+const SignatureHeader = "X-Calc-Signature"
+
+var (
+	ErrInvalidCallbackURL = errors.New("некорректный callback_url")
+	// ErrCallbacksDisabled — callback_url передан, но CALLBACK_SECRET не задан:
+	// получатель не смог бы проверить, что запрос отправил оркестратор.
+	ErrCallbacksDisabled = errors.New("callback выключены: не задан CALLBACK_SECRET")
+)
+
+// DeadLetter — callback, который не удалось доставить за все попытки.
+type DeadLetter struct {
+	ExpressionID int       `json:"expression_id"`
+	CallbackURL  string    `json:"callback_url"`
+	Payload      string    `json:"payload"`
+	Attempts     int       `json:"attempts"`
+	LastError    string    `json:"last_error"`
+	FailedAt     time.Time `json:"failed_at"`
+}
+
+type webhookPayload struct {
+	ID     int     `json:"id"`
+	Status string  `json:"status"`
+	Result float64 `json:"result"`
+	// Error — причина, по которой выражение завершилось со статусом error.
+	Error string `json:"error,omitempty"`
+}
+
+// webhook — callback, ожидающий отправки.
+type webhook struct {
+	exprID int
+	url    string
+	body   []byte
+}
+
+type webhookSender struct {
+	logger         *slog.Logger
+	client         *http.Client
+	secret         []byte
+	maxAttempts    int
+	backoff        time.Duration
+	workers        int
+	maxDeadLetters int
+	queue          chan webhook
+	startWorkers   sync.Once
+
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+}
+
+// newWebhookSender читает настройки из переменных среды: CALLBACK_SECRET,
+// CALLBACK_MAX_ATTEMPTS, CALLBACK_BACKOFF_MS, CALLBACK_WORKERS (по умолчанию 8),
+// CALLBACK_QUEUE_SIZE (по умолчанию 1000) и CALLBACK_DEAD_LETTERS_MAX (по умолчанию 1000).
+func newWebhookSender(logger *slog.Logger) *webhookSender {
+	return &webhookSender{
+		logger:         logger,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		secret:         []byte(os.Getenv("CALLBACK_SECRET")),
+		maxAttempts:    getEnvInt("CALLBACK_MAX_ATTEMPTS", 5),
+		backoff:        time.Duration(getEnvInt("CALLBACK_BACKOFF_MS", 500)) * time.Millisecond,
+		workers:        max(1, getEnvInt("CALLBACK_WORKERS", 8)),
+		maxDeadLetters: max(1, getEnvInt("CALLBACK_DEAD_LETTERS_MAX", 1000)),
+		queue:          make(chan webhook, max(1, getEnvInt("CALLBACK_QUEUE_SIZE", 1000))),
+	}
+}
+
+// validate проверяет, что на callbackURL можно отправить подписанный callback.
+func (s *webhookSender) validate(callbackURL string) error {
+	if len(s.secret) == 0 {
+		return ErrCallbacksDisabled
+	}
+	return validateCallbackURL(callbackURL)
+}
+
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidCallbackURL, raw)
+	}
+	return nil
+}
+
+// Sign возвращает значение заголовка подписи для тела запроса.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueue ставит callback в очередь на отправку. Вызывается под o.mu, поэтому
+// не ждёт: если очередь заполнена, callback сразу попадает в список недоставленных.
+func (s *webhookSender) enqueue(callbackURL string, expr Expression) {
+	body, _ := json.Marshal(webhookPayload{ID: expr.ID, Status: expr.Status, Result: expr.Result, Error: expr.Error})
+	s.startWorkers.Do(func() {
+		for range s.workers {
+			go s.run()
+		}
+	})
+
+	select {
+	case s.queue <- webhook{exprID: expr.ID, url: callbackURL, body: body}:
+	default:
+		s.logger.Error("очередь callback переполнена", logging.ExpressionID(expr.ID), "url", callbackURL)
+		s.addDeadLetter(webhook{exprID: expr.ID, url: callbackURL, body: body}, 0, errors.New("очередь callback переполнена"))
+	}
+}
+
+// run отправляет callback'и из очереди.
+func (s *webhookSender) run() {
+	for hook := range s.queue {
+		s.deliver(hook)
+	}
+}
+
+// deliver отправляет callback с экспоненциальной задержкой между попытками.
+// После исчерпания попыток callback попадает в список недоставленных.
+func (s *webhookSender) deliver(hook webhook) {
+	var lastErr error
+	delay := s.backoff
+
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		lastErr = s.post(hook.url, hook.body)
+		if lastErr == nil {
+			s.logger.Info("callback доставлен", logging.ExpressionID(hook.exprID), "url", hook.url, "attempt", attempt)
+			return
+		}
+
+		s.logger.Warn("ошибка доставки callback", logging.ExpressionID(hook.exprID), "url", hook.url, "attempt", attempt, logging.Err(lastErr))
+		if attempt < s.maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	s.logger.Error("callback не доставлен", logging.ExpressionID(hook.exprID), "url", hook.url, "attempts", s.maxAttempts)
+	s.addDeadLetter(hook, s.maxAttempts, lastErr)
+}
+
+// addDeadLetter добавляет callback в список недоставленных. Список хранит
+// не больше maxDeadLetters записей: самые старые вытесняются.
+func (s *webhookSender) addDeadLetter(hook webhook, attempts int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.deadLetters) >= s.maxDeadLetters {
+		s.deadLetters = slices.Delete(s.deadLetters, 0, len(s.deadLetters)-s.maxDeadLetters+1)
+	}
+	s.deadLetters = append(s.deadLetters, DeadLetter{
+		ExpressionID: hook.exprID,
+		CallbackURL:  hook.url,
+		Payload:      string(hook.body),
+		Attempts:     attempts,
+		LastError:    err.Error(),
+		FailedAt:     time.Now(),
+	})
+}
+
+func (s *webhookSender) post(callbackURL string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("код ответа: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *webhookSender) list() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter{}, s.deadLetters...)
+}
+
+// GetDeadLetters возвращает callback'и, которые не удалось доставить.
+func (o *Orchestrator) GetDeadLetters() []DeadLetter {
+	return o.webhooks.list()
+}
+
+func (o *Orchestrator) HandleGetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]DeadLetter{"dead_letters": o.GetDeadLetters()})
+}
@@ -0,0 +1,181 @@
+package orchestrator_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	t.Setenv("CALLBACK_SECRET", "secret")
+	t.Setenv("CALLBACK_MAX_ATTEMPTS", "2")
+	t.Setenv("CALLBACK_BACKOFF_MS", "1")
+
+	tests := []struct {
+		name           string
+		receiverStatus int
+		wantDelivered  bool
+	}{
+		{"Успешная доставка", http.StatusOK, true},
+		{"Недоставленный callback", http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 4)
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get(orchestrator.SignatureHeader) != orchestrator.Sign([]byte("secret"), body) {
+					t.Errorf("❌ %s: неверная подпись callback", tt.name)
+				}
+				w.WriteHeader(tt.receiverStatus)
+				received <- body
+			}))
+			defer receiver.Close()
+
+			o := orchestrator.NewOrchestrator()
+			id, err := o.AddExpressionWithOptions("2+2", orchestrator.ExpressionOptions{CallbackURL: receiver.URL})
+			if err != nil {
+				t.Fatalf("❌ %s: не ожидали ошибку, но получили: %v", tt.name, err)
+			}
+
+			task, _ := o.GetNextTask()
+			o.HandleTaskResult(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/internal/task",
+				strings.NewReader(fmt.Sprintf(`{"id": %d, "result": %g}`, task.ID, task.Arg1+task.Arg2))))
+
+			select {
+			case body := <-received:
+				var payload struct {
+					ID     int     `json:"id"`
+					Status string  `json:"status"`
+					Result float64 `json:"result"`
+				}
+				json.Unmarshal(body, &payload)
+				if payload.ID != id || payload.Status != orchestrator.StatusDone || payload.Result != 4 {
+					t.Fatalf("❌ %s: неожиданное тело callback: %s", tt.name, body)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("❌ %s: callback не получен", tt.name)
+			}
+
+			if !tt.wantDelivered {
+				deadline := time.Now().Add(2 * time.Second)
+				for len(o.GetDeadLetters()) == 0 && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+				}
+				letters := o.GetDeadLetters()
+				if len(letters) != 1 || letters[0].ExpressionID != id || letters[0].Attempts != 2 {
+					t.Fatalf("❌ %s: ожидали одну запись в dead-letter, а получили %+v", tt.name, letters)
+				}
+			}
+
+			fmt.Printf("✅ %s: callback обработан корректно\n", tt.name)
+		})
+	}
+}
+
+func TestWebhookRequiresSecret(t *testing.T) {
+	o := orchestrator.NewOrchestrator()
+	_, err := o.AddExpressionWithOptions("2+2", orchestrator.ExpressionOptions{CallbackURL: "http://localhost:9000/hook"})
+	if !errors.Is(err, orchestrator.ErrCallbacksDisabled) {
+		t.Fatalf("❌ ожидали отказ без CALLBACK_SECRET, а получили %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	o.HandleCalculate(rec, httptest.NewRequest(http.MethodPost, "/api/v1/calculate",
+		strings.NewReader(`{"expression": "2+2", "callback_url": "http://localhost:9000/hook"}`)))
+	if rec.Code != http.StatusBadRequest || len(o.GetAllExpressions()) != 0 {
+		t.Fatalf("❌ ожидали 400 без добавления выражения, а получили %d %s", rec.Code, rec.Body)
+	}
+	fmt.Println("✅ Без CALLBACK_SECRET callback_url отклоняется")
+}
+
+func TestWebhookErrorReason(t *testing.T) {
+	t.Setenv("CALLBACK_SECRET", "secret")
+	received := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer receiver.Close()
+
+	o := orchestrator.NewOrchestrator()
+	o.AddExpressionWithOptions("4/2", orchestrator.ExpressionOptions{CallbackURL: receiver.URL})
+	task := o.NextTasks("agent-1", 1)[0]
+	o.RecordResults("agent-1", []models.TaskResult{{ID: task.ID, Error: "деление на ноль"}})
+
+	select {
+	case body := <-received:
+		var payload struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		json.Unmarshal(body, &payload)
+		if payload.Status != orchestrator.StatusError || !strings.Contains(payload.Error, "деление на ноль") {
+			t.Fatalf("❌ в callback нет причины ошибки: %s", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("❌ callback не получен")
+	}
+	fmt.Println("✅ Callback выражения с ошибкой содержит её причину")
+}
+
+func TestWebhookLimits(t *testing.T) {
+	t.Setenv("CALLBACK_SECRET", "secret")
+	t.Setenv("CALLBACK_MAX_ATTEMPTS", "1")
+	t.Setenv("CALLBACK_WORKERS", "1")
+	t.Setenv("CALLBACK_QUEUE_SIZE", "1")
+	t.Setenv("CALLBACK_DEAD_LETTERS_MAX", "2")
+
+	// Получатель отвечает на первый запрос, только когда тест разрешит.
+	release := make(chan struct{})
+	var requests atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			<-release
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	o := orchestrator.NewOrchestrator()
+	var ids []int
+	for i := 1; i <= 4; i++ {
+		id, _ := o.AddExpressionWithOptions(fmt.Sprintf("%d+%d", i, i), orchestrator.ExpressionOptions{CallbackURL: receiver.URL})
+		ids = append(ids, id)
+		if i == 1 {
+			// Единственный воркер занят первым callback, второй ждёт в очереди.
+			task := o.NextTasks("agent-1", 1)[0]
+			o.RecordResults("agent-1", []models.TaskResult{{ID: task.ID, Result: 2}})
+			waitFor(t, "первый callback", func() bool { return requests.Load() == 1 })
+		}
+	}
+	for range 3 {
+		task := o.NextTasks("agent-1", 1)[0]
+		o.RecordResults("agent-1", []models.TaskResult{{ID: task.ID, Result: task.Arg1 + task.Arg2}})
+	}
+
+	// Очередь переполнена: третий и четвёртый callback недоставлены без попыток.
+	letters := o.GetDeadLetters()
+	if len(letters) != 2 || letters[0].ExpressionID != ids[2] || letters[1].ExpressionID != ids[3] || letters[0].Attempts != 0 {
+		t.Fatalf("❌ ожидали два callback из переполненной очереди, а получили %+v", letters)
+	}
+	fmt.Println("✅ Callback сверх очереди сразу попадают в список недоставленных")
+
+	// Первые два callback тоже не доставлены и вытесняют самые старые записи.
+	close(release)
+	waitFor(t, "вытеснение старых записей", func() bool {
+		letters = o.GetDeadLetters()
+		return len(letters) == 2 && letters[0].ExpressionID == ids[0] && letters[1].ExpressionID == ids[1]
+	})
+	fmt.Println("✅ Список недоставленных callback ограничен")
+}
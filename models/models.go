@@ -4,8 +4,42 @@ import "time"
 
 type Task struct {
 	ID            int           `json:"id"`
+	ExpressionID  int           `json:"expression_id"`
 	Arg1          float64       `json:"arg1"`
 	Arg2          float64       `json:"arg2"`
 	Operation     string        `json:"operation"`
 	OperationTime time.Duration `json:"operation_time"`
+	// TraceContext — контекст трассировки задачи (W3C traceparent и tracestate).
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+// AgentIDHeader — заголовок, которым агент представляется оркестратору.
+const AgentIDHeader = "X-Agent-ID"
+
+// MaxBatchSize — максимальное количество задач или результатов в одном запросе
+// агента к оркестратору.
+const MaxBatchSize = 100
+
+// TaskResult — результат выполнения задачи, который агент отправляет оркестратору.
+type TaskResult struct {
+	ID     int     `json:"id"`
+	Result float64 `json:"result"`
+	// ComputeTime — время выполнения задачи агентом в наносекундах.
+	ComputeTime time.Duration `json:"compute_time"`
+	// Error — ошибка, с которой агент не смог выполнить задачу. Повторное
+	// выполнение не поможет, поэтому выражение завершается со статусом error.
+	Error string `json:"error,omitempty"`
+}
+
+// Статусы подтверждения результата.
+const (
+	AckOK    = "ok"
+	AckError = "error"
+)
+
+// Ack — подтверждение оркестратором одного результата из пачки.
+type Ack struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
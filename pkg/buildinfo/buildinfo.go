@@ -0,0 +1,60 @@
+// Package buildinfo сообщает версию сборки. Значения задаются при сборке:
+//
+//	go build -ldflags "-X Calc_2GO/pkg/buildinfo.Version=1.2.0 -X Calc_2GO/pkg/buildinfo.Commit=$(git rev-parse HEAD) -X Calc_2GO/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Без них коммит и время берутся из сведений о VCS, которые go build встраивает сам.
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info — сведения о сборке, которые отдаёт /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	// Modified — сборка из рабочей копии с незакоммиченными изменениями.
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Get возвращает сведения о текущей сборке.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// Handler отдаёт info в формате JSON.
+func Handler(info Info) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
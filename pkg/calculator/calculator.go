@@ -1,7 +1,7 @@
 package calculator
 
 import (
-	models "Calc_2GO/Models"
+	models "Calc_2GO/models"
 	"errors"
 	"fmt"
 	"strconv"
@@ -17,23 +17,94 @@ var (
 	ErrInvalidCharacter  = errors.New("invalid character")
 )
 
+// ErrLimitExceeded — выражение превышает одно из ограничений Limits. Подробности — в *LimitError.
+var ErrLimitExceeded = errors.New("expression limit exceeded")
+
+// Ограничения, которые может превысить выражение.
+const (
+	LimitLength = "length"
+	LimitTokens = "tokens"
+	LimitDepth  = "depth"
+	LimitTasks  = "tasks"
+)
+
+// Limits ограничивает размер и сложность выражения. Нулевое значение — без ограничения.
+type Limits struct {
+	// MaxLength — длина выражения в байтах.
+	MaxLength int
+	MaxTokens int
+	// MaxDepth — глубина вложенности скобок.
+	MaxDepth int
+	// MaxTasks — количество задач, на которые разбивается выражение.
+	MaxTasks int
+}
+
+// LimitError сообщает, какое ограничение превышено и насколько.
+type LimitError struct {
+	Limit  string
+	Max    int
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s %d > %d", ErrLimitExceeded, e.Limit, e.Actual, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+func checkLimit(limit string, max, actual int) error {
+	if max > 0 && actual > max {
+		return &LimitError{Limit: limit, Max: max, Actual: actual}
+	}
+	return nil
+}
+
 // CalcToTasks разбивает входную строку на токены, переводит их в постфиксную нотацию
-// и создает массив Task, где каждый Task содержит операцию (Arg1 op Arg2) с общим ID.
+// и создает массив Task, где каждый Task содержит операцию (Arg1 op Arg2) с общим ExpressionID.
+// Последняя задача массива вычисляет значение всего выражения.
 func CalcToTasks(id int, expression string) ([]models.Task, error) {
+	return CalcToTasksWithLimits(id, expression, Limits{})
+}
+
+// CalcToTasksWithLimits работает как CalcToTasks, но отклоняет выражения, превышающие limits,
+// до того как разбить их на задачи.
+func CalcToTasksWithLimits(id int, expression string, limits Limits) ([]models.Task, error) {
 	if expression == "" {
 		return nil, ErrInvalidExpression
 	}
+	if err := checkLimit(LimitLength, limits.MaxLength, len(expression)); err != nil {
+		return nil, err
+	}
 
 	tokens, err := tokenize(expression)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkLimit(LimitTokens, limits.MaxTokens, len(tokens)); err != nil {
+		return nil, err
+	}
+	if err := checkLimit(LimitDepth, limits.MaxDepth, nestingDepth(tokens)); err != nil {
+		return nil, err
+	}
 
 	postfix, err := infixToPostfix(tokens)
 	if err != nil {
 		return nil, err
 	}
 
+	// Каждый оператор постфиксной записи становится одной задачей.
+	operators := 0
+	for _, token := range postfix {
+		if isOperator(token) {
+			operators++
+		}
+	}
+	if err := checkLimit(LimitTasks, limits.MaxTasks, operators); err != nil {
+		return nil, err
+	}
+
 	tasks, err := evaluatePostfixToTasks(id, postfix)
 	if err != nil {
 		return nil, err
@@ -73,6 +144,21 @@ func tokenize(expr string) ([]string, error) {
 	return tokens, nil
 }
 
+// nestingDepth возвращает наибольшую глубину вложенности скобок.
+func nestingDepth(tokens []string) int {
+	depth, maxDepth := 0, 0
+	for _, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+			maxDepth = max(maxDepth, depth)
+		case ")":
+			depth--
+		}
+	}
+	return maxDepth
+}
+
 func infixToPostfix(tokens []string) ([]string, error) {
 	var output []string
 	var operators []string
@@ -134,7 +220,7 @@ func evaluatePostfixToTasks(id int, postfix []string) ([]models.Task, error) {
 
 			// Формируем задачу
 			t := models.Task{
-				ID:            id,
+				ExpressionID:  id,
 				Arg1:          a,
 				Arg2:          b,
 				Operation:     token,
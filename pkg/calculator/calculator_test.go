@@ -1,8 +1,10 @@
 package calculator_test
 
 import (
-	"Calc_distrib/pkg/calculator"
+	"Calc_2GO/pkg/calculator"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -33,7 +35,7 @@ func TestCalc(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := calculator.Calc(tt.expression)
+			got, err := calc(tt.expression)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatalf("❌ %s: ожидалась ошибка, но получили результат: %v", tt.name, got)
@@ -54,3 +56,27 @@ func TestCalc(t *testing.T) {
 		})
 	}
 }
+
+// calc вычисляет выражение по задачам, на которые его разбивает CalcToTasks:
+// последняя задача вычисляет значение всего выражения.
+func calc(expression string) (float64, error) {
+	tasks, err := calculator.CalcToTasks(1, expression)
+	if err != nil {
+		return 0, err
+	}
+	if len(tasks) == 0 {
+		return strconv.ParseFloat(strings.ReplaceAll(expression, " ", ""), 64)
+	}
+
+	last := tasks[len(tasks)-1]
+	switch last.Operation {
+	case "+":
+		return last.Arg1 + last.Arg2, nil
+	case "-":
+		return last.Arg1 - last.Arg2, nil
+	case "*":
+		return last.Arg1 * last.Arg2, nil
+	default:
+		return last.Arg1 / last.Arg2, nil
+	}
+}
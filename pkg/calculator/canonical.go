@@ -0,0 +1,67 @@
+package calculator
+
+import (
+	models "Calc_2GO/models"
+	"strconv"
+)
+
+// Canonicalize приводит выражение к канонической записи: числа — в кратчайшей
+// десятичной форме, каждая операция — в скобках, операнды сложения и умножения
+// упорядочены. Выражения с одинаковой канонической записью имеют одинаковое значение,
+// например "2 + 3*4" и "(4*3)+2" записываются как "((3*4)+2)".
+func Canonicalize(expression string) (string, error) {
+	if expression == "" {
+		return "", ErrInvalidExpression
+	}
+
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return "", err
+	}
+	postfix, err := infixToPostfix(tokens)
+	if err != nil {
+		return "", err
+	}
+
+	var stack []string
+	for _, token := range postfix {
+		if !isOperator(token) {
+			num, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return "", ErrInvalidToken
+			}
+			stack = append(stack, formatNumber(num))
+			continue
+		}
+
+		if len(stack) < 2 {
+			return "", ErrInvalidExpression
+		}
+		a, b := stack[len(stack)-2], stack[len(stack)-1]
+		stack = append(stack[:len(stack)-2], canonicalOperation(a, token, b))
+	}
+
+	if len(stack) != 1 {
+		return "", ErrInvalidExpression
+	}
+	return stack[0], nil
+}
+
+// TaskKey возвращает каноническую запись операции задачи. Она совпадает
+// с канонической записью любого подвыражения, которое вычисляет задача.
+func TaskKey(task models.Task) string {
+	return canonicalOperation(formatNumber(task.Arg1), task.Operation, formatNumber(task.Arg2))
+}
+
+// canonicalOperation записывает операцию над каноническими операндами. Сложение
+// и умножение чисел с плавающей точкой коммутативны, поэтому их операнды упорядочиваются.
+func canonicalOperation(a, op, b string) string {
+	if (op == "+" || op == "*") && b < a {
+		a, b = b, a
+	}
+	return "(" + a + op + b + ")"
+}
+
+func formatNumber(num float64) string {
+	return strconv.FormatFloat(num, 'g', -1, 64)
+}
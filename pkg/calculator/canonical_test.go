@@ -0,0 +1,59 @@
+package calculator_test
+
+import (
+	"Calc_2GO/pkg/calculator"
+	"fmt"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+	}{
+		{"Пробелы", "2 + 3", "(2+3)"},
+		{"Перестановка слагаемых", "3+2", "(2+3)"},
+		{"Перестановка множителей", "(4*3)+2", "((3*4)+2)"},
+		{"Приоритет операций", "2+3*4", "((3*4)+2)"},
+		{"Лишние скобки", "((2+3))", "(2+3)"},
+		{"Запись чисел", "2.50+1.0", "(1+2.5)"},
+		{"Вычитание не коммутативно", "3-2", "(3-2)"},
+		{"Деление не коммутативно", "2/4", "(2/4)"},
+		{"Отрицательные числа", "(-2)+1", "(-2+1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculator.Canonicalize(tt.expression)
+			if err != nil || got != tt.want {
+				t.Fatalf("❌ %s: ожидали %q, а получили %q %v", tt.name, tt.want, got, err)
+			}
+			fmt.Printf("✅ %s: %s → %s\n", tt.name, tt.expression, got)
+		})
+	}
+
+	if _, err := calculator.Canonicalize("(2+3"); err == nil {
+		t.Fatal("❌ ожидали ошибку для несбалансированных скобок")
+	}
+}
+
+func TestTaskKey(t *testing.T) {
+	tasks, err := calculator.CalcToTasks(1, "(1+2)*(4+3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"(1+2)", "(3+4)", "(3*7)"}
+	for i, task := range tasks {
+		if key := calculator.TaskKey(task); key != want[i] {
+			t.Fatalf("❌ задача №%d: ожидали ключ %q, а получили %q", i+1, want[i], key)
+		}
+	}
+
+	// Ключ задачи совпадает с канонической записью вычисляемого ею подвыражения.
+	if key, _ := calculator.Canonicalize("4+3"); key != calculator.TaskKey(tasks[1]) {
+		t.Fatalf("❌ ключ задачи %q не совпадает с канонической записью %q", calculator.TaskKey(tasks[1]), key)
+	}
+	fmt.Println("✅ Ключи задач совпадают с каноническими записями подвыражений")
+}
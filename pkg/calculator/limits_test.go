@@ -0,0 +1,41 @@
+package calculator_test
+
+import (
+	"Calc_2GO/pkg/calculator"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCalcToTasksLimits(t *testing.T) {
+	limits := calculator.Limits{MaxLength: 100, MaxTokens: 20, MaxDepth: 3, MaxTasks: 5}
+
+	tests := []struct {
+		name       string
+		expression string
+		wantLimit  string
+	}{
+		{"В пределах ограничений", "((1+2)*3)-4", ""},
+		{"Слишком длинное выражение", strings.Repeat("1", 101), calculator.LimitLength},
+		{"Слишком много токенов", strings.Repeat("1+", 10) + "1", calculator.LimitTokens},
+		{"Слишком глубокая вложенность", "((((1+2))))", calculator.LimitDepth},
+		{"Слишком много задач", "1+1+1+1+1+1+1", calculator.LimitTasks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := calculator.CalcToTasksWithLimits(1, tt.expression, limits)
+
+			var limitErr *calculator.LimitError
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("❌ %s: неожиданная ошибка: %v", tt.name, err)
+				}
+			} else if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit || !errors.Is(err, calculator.ErrLimitExceeded) {
+				t.Fatalf("❌ %s: ожидали превышение ограничения %s, а получили %v", tt.name, tt.wantLimit, err)
+			}
+			fmt.Printf("✅ %s: %v\n", tt.name, err)
+		})
+	}
+}
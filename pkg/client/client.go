@@ -0,0 +1,231 @@
+// Package client — клиент публичного API оркестратора:
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(key))
+//	id, err := c.Submit(ctx, "2+2*2", client.SubmitOptions{})
+//	expr, err := c.Wait(ctx, id)
+//
+// Ошибки, которые вернул оркестратор, имеют тип *APIError и сопоставляются
+// с ошибками Err* через errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client — клиент публичного API оркестратора. Безопасен для одновременного использования.
+type Client struct {
+	baseURL      string
+	apiKey       string
+	http         *http.Client
+	pollInterval time.Duration
+}
+
+// Option настраивает клиента при создании.
+type Option func(*Client)
+
+// WithAPIKey задаёт API-ключ, который передаётся в заголовке Authorization.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient задаёт HTTP-клиент, например с настроенным TLS.
+// По умолчанию используется http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithPollInterval задаёт период опроса в Wait, если поток событий недоступен.
+// По умолчанию — 500 мс.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = d
+	}
+}
+
+// New создаёт клиента оркестратора с адресом публичного API baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		http:         http.DefaultClient,
+		pollInterval: 500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Submit отправляет выражение на вычисление и возвращает его ID.
+func (c *Client) Submit(ctx context.Context, expression string, opts SubmitOptions) (int, error) {
+	body, _ := json.Marshal(struct {
+		Expression  string `json:"expression"`
+		CallbackURL string `json:"callback_url,omitempty"`
+	}{expression, opts.CallbackURL})
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/calculate", body, &response); err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(response.ID)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный id в ответе: %q", response.ID)
+	}
+	return id, nil
+}
+
+// SubmitBatch отправляет выражения по очереди и возвращает итог для каждого из них.
+// Ошибка одного выражения не прерывает отправку остальных; отмена ctx — прерывает.
+func (c *Client) SubmitBatch(ctx context.Context, expressions []string, opts SubmitOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, 0, len(expressions))
+	for _, expression := range expressions {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		id, err := c.Submit(ctx, expression, opts)
+		results = append(results, BatchResult{ID: id, Err: err})
+	}
+	return results, nil
+}
+
+// Get возвращает выражение по ID.
+func (c *Client) Get(ctx context.Context, id int) (*Expression, error) {
+	var expr Expression
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/expressions/%d", id), nil, &expr); err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// Tasks возвращает задачи выражения в порядке вычисления.
+func (c *Client) Tasks(ctx context.Context, id int) ([]Task, error) {
+	var response struct {
+		Tasks []Task `json:"tasks"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/expressions/%d/tasks", id), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Tasks, nil
+}
+
+// List возвращает страницу выражений, подходящих под фильтр.
+func (c *Client) List(ctx context.Context, filter ListFilter) (*Page, error) {
+	q := url.Values{}
+	if filter.Status != "" {
+		q.Set("status", filter.Status)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q.Set("created_after", filter.CreatedAfter.Format(time.RFC3339))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q.Set("created_before", filter.CreatedBefore.Format(time.RFC3339))
+	}
+	if filter.Sort != "" {
+		q.Set("sort", filter.Sort)
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Cursor != "" {
+		q.Set("cursor", filter.Cursor)
+	}
+
+	path := "/api/v1/expressions"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var page Page
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Cancel отменяет выражение и возвращает его состояние после отмены.
+// Если выражение уже завершено, возвращается ошибка ErrConflict.
+func (c *Client) Cancel(ctx context.Context, id int) (*Expression, error) {
+	var expr Expression
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/expressions/%d/cancel", id), nil, &expr); err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// Agents возвращает агентов, зарегистрированных в оркестраторе.
+func (c *Client) Agents(ctx context.Context) ([]Agent, error) {
+	var response struct {
+		Agents []Agent `json:"agents"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/agents", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Agents, nil
+}
+
+// do выполняет запрос и декодирует ответ в out.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("ошибка при декодировании ответа: %w", err)
+	}
+	return nil
+}
+
+// send выполняет запрос и возвращает ответ с кодом 2xx. Остальные ответы
+// превращаются в *APIError.
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(message)), "❌")),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return nil, apiErr
+}
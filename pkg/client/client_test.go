@@ -0,0 +1,183 @@
+package client_test
+
+import (
+	"Calc_2GO/internal/orchestrator"
+	models "Calc_2GO/models"
+	"Calc_2GO/pkg/client"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newServer запускает оркестратор за httptest-сервером. wrap позволяет подменить ответы.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) (*orchestrator.Orchestrator, *client.Client) {
+	t.Helper()
+	o := orchestrator.NewOrchestrator()
+	handler := o.PublicHandler()
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return o, client.New(ts.URL, client.WithPollInterval(10*time.Millisecond))
+}
+
+// solve выполняет задачи из очереди оркестратора, пока они не закончатся.
+func solve(o *orchestrator.Orchestrator) {
+	for {
+		tasks := o.NextTasks("agent-1", models.MaxBatchSize)
+		if len(tasks) == 0 {
+			return
+		}
+		results := make([]models.TaskResult, len(tasks))
+		for i, task := range tasks {
+			var result float64
+			switch task.Operation {
+			case "+":
+				result = task.Arg1 + task.Arg2
+			case "-":
+				result = task.Arg1 - task.Arg2
+			case "*":
+				result = task.Arg1 * task.Arg2
+			case "/":
+				result = task.Arg1 / task.Arg2
+			}
+			results[i] = models.TaskResult{ID: task.ID, Result: result}
+		}
+		o.RecordResults("agent-1", results)
+	}
+}
+
+func TestSubmitAndWait(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(http.Handler) http.Handler
+	}{
+		{"Поток событий", nil},
+		{"Опрос, если поток недоступен", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/events") {
+					http.Error(w, "❌ Потоковая передача не поддерживается", http.StatusInternalServerError)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, c := newServer(t, tt.wrap)
+			ctx := context.Background()
+
+			id, err := c.Submit(ctx, "2+2*2", client.SubmitOptions{})
+			if err != nil {
+				t.Fatalf("❌ не ожидали ошибку, но получили: %v", err)
+			}
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				solve(o)
+			}()
+
+			ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+			defer cancel()
+			expr, err := c.Wait(ctx, id)
+			if err != nil || expr.Status != client.StatusDone || expr.Result != 6 {
+				t.Fatalf("❌ ожидали результат 6, а получили %+v %v", expr, err)
+			}
+
+			tasks, err := c.Tasks(ctx, id)
+			if err != nil || len(tasks) != 2 || tasks[1].Result == nil || *tasks[1].Result != 6 {
+				t.Fatalf("❌ неожиданные задачи %+v %v", tasks, err)
+			}
+			fmt.Printf("✅ %s: %s = %g\n", tt.name, expr.Expression, expr.Result)
+		})
+	}
+}
+
+func TestListCancelAndBatch(t *testing.T) {
+	o, c := newServer(t, nil)
+	ctx := context.Background()
+
+	results, err := c.SubmitBatch(ctx, []string{"1+1", "2+2", "3+3"}, client.SubmitOptions{})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("❌ ожидали 3 результата, а получили %+v %v", results, err)
+	}
+	for i, res := range results {
+		if res.Err != nil || res.ID != i+1 {
+			t.Fatalf("❌ выражение №%d: %+v", i+1, res)
+		}
+	}
+	fmt.Println("✅ Пачка выражений отправлена")
+
+	expr, err := c.Cancel(ctx, 2)
+	if err != nil || expr.Status != client.StatusCancelled {
+		t.Fatalf("❌ ожидали отмену выражения, а получили %+v %v", expr, err)
+	}
+	if _, err := c.Cancel(ctx, 2); !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("❌ ожидали ErrConflict при повторной отмене, а получили %v", err)
+	}
+	fmt.Println("✅ Выражение отменено")
+
+	solve(o)
+	page, err := c.List(ctx, client.ListFilter{Status: client.StatusDone, Sort: "-id", Limit: 1})
+	if err != nil || len(page.Expressions) != 1 || page.Expressions[0].ID != 3 || page.NextCursor == "" {
+		t.Fatalf("❌ неожиданная первая страница %+v %v", page, err)
+	}
+	page, err = c.List(ctx, client.ListFilter{Status: client.StatusDone, Sort: "-id", Limit: 1, Cursor: page.NextCursor})
+	if err != nil || len(page.Expressions) != 1 || page.Expressions[0].ID != 1 || page.NextCursor != "" {
+		t.Fatalf("❌ неожиданная вторая страница %+v %v", page, err)
+	}
+	fmt.Println("✅ Список выражений с фильтром и курсором")
+}
+
+func TestErrors(t *testing.T) {
+	_, c := newServer(t, nil)
+	if _, err := c.Get(context.Background(), 42); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("❌ ожидали ErrNotFound, а получили %v", err)
+	}
+	if _, err := c.Wait(context.Background(), 42); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("❌ ожидали, что Wait не ждёт несуществующее выражение, а получили %v", err)
+	}
+	for _, expr := range []string{"", "2++2", "(1+2", "2+a"} {
+		_, err := c.Submit(context.Background(), expr, client.SubmitOptions{})
+		if !errors.Is(err, client.ErrInvalidExpression) {
+			t.Fatalf("❌ выражение %q: ожидали ErrInvalidExpression, а получили %v", expr, err)
+		}
+		fmt.Printf("✅ %q → %v\n", expr, err)
+	}
+
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, client.ErrBadRequest},
+		{http.StatusUnauthorized, client.ErrUnauthorized},
+		{http.StatusForbidden, client.ErrForbidden},
+		{http.StatusRequestEntityTooLarge, client.ErrTooLarge},
+		{http.StatusUnprocessableEntity, client.ErrInvalidExpression},
+		{http.StatusTooManyRequests, client.ErrRateLimited},
+		{http.StatusServiceUnavailable, client.ErrUnavailable},
+		{http.StatusInternalServerError, client.ErrServer},
+	}
+
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3")
+			http.Error(w, "❌ ошибка", tt.status)
+		}))
+		_, err := client.New(ts.URL).Submit(context.Background(), "1+1", client.SubmitOptions{})
+		ts.Close()
+
+		var apiErr *client.APIError
+		if !errors.Is(err, tt.want) || !errors.As(err, &apiErr) || apiErr.Message != "ошибка" || apiErr.RetryAfter != 3*time.Second {
+			t.Fatalf("❌ код %d: ожидали %v, а получили %#v", tt.status, tt.want, err)
+		}
+		fmt.Printf("✅ %d → %v\n", tt.status, tt.want)
+	}
+}
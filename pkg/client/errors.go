@@ -0,0 +1,69 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ошибки, соответствующие кодам ответа оркестратора. Проверяются через errors.Is:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+var (
+	ErrBadRequest        = errors.New("некорректный запрос")
+	ErrUnauthorized      = errors.New("требуется API-ключ")
+	ErrForbidden         = errors.New("недостаточно прав")
+	ErrNotFound          = errors.New("не найдено")
+	ErrConflict          = errors.New("конфликт состояния")
+	ErrTooLarge          = errors.New("запрос слишком большой")
+	ErrInvalidExpression = errors.New("некорректное выражение")
+	ErrRateLimited       = errors.New("превышен лимит запросов")
+	ErrUnavailable       = errors.New("оркестратор недоступен")
+	ErrServer            = errors.New("внутренняя ошибка оркестратора")
+)
+
+// APIError — оркестратор ответил кодом ошибки.
+type APIError struct {
+	StatusCode int
+	// Message — текст ошибки из ответа оркестратора.
+	Message string
+	// RetryAfter — через сколько можно повторить запрос, если оркестратор это сообщил.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("оркестратор ответил %d: %s", e.StatusCode, e.Message)
+}
+
+// Is сопоставляет код ответа с ошибками Err*.
+func (e *APIError) Is(target error) bool {
+	return statusError(e.StatusCode) == target
+}
+
+func statusError(code int) error {
+	switch code {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrTooLarge
+	case http.StatusUnprocessableEntity:
+		return ErrInvalidExpression
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+	if code >= 500 {
+		return ErrServer
+	}
+	return nil
+}
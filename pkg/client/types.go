@@ -0,0 +1,102 @@
+package client
+
+import "time"
+
+// Статусы выражения. done, error и cancelled — финальные.
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusError      = "error"
+	StatusCancelled  = "cancelled"
+)
+
+// IsFinal сообщает, что выражение в этом статусе больше не изменится.
+func IsFinal(status string) bool {
+	return status == StatusDone || status == StatusError || status == StatusCancelled
+}
+
+// Expression — выражение и ход его вычисления.
+type Expression struct {
+	ID         int     `json:"id"`
+	Expression string  `json:"expression"`
+	Status     string  `json:"status"`
+	Result     float64 `json:"result"`
+	Owner      string  `json:"owner,omitempty"`
+	// Error — причина, по которой выражение завершилось со статусом error.
+	Error string `json:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	TasksTotal    int   `json:"tasks_total"`
+	TasksDone     int   `json:"tasks_done"`
+	ComputeTimeMs int64 `json:"compute_time_ms"`
+	QueueWaitMs   int64 `json:"queue_wait_ms"`
+	// Cached — результат взят из кэша оркестратора без вычисления.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Task — задача выражения: одна арифметическая операция.
+type Task struct {
+	ID           int     `json:"id"`
+	ExpressionID int     `json:"expression_id"`
+	Arg1         float64 `json:"arg1"`
+	Arg2         float64 `json:"arg2"`
+	Operation    string  `json:"operation"`
+	Status       string  `json:"status"`
+	// Agent — агент, которому задача выдана последней.
+	Agent    string `json:"agent,omitempty"`
+	Attempts int    `json:"attempts"`
+
+	QueuedAt   time.Time  `json:"queued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	Result *float64 `json:"result,omitempty"`
+	Error  string   `json:"error,omitempty"`
+	Cached bool     `json:"cached,omitempty"`
+}
+
+// Agent — агент, зарегистрированный в оркестраторе.
+type Agent struct {
+	ID             string    `json:"id"`
+	Hostname       string    `json:"hostname"`
+	ComputingPower int       `json:"computing_power"`
+	Operations     []string  `json:"operations"`
+	Version        string    `json:"version"`
+	Status         string    `json:"status"`
+	RegisteredAt   time.Time `json:"registered_at"`
+	LastHeartbeat  time.Time `json:"last_heartbeat"`
+}
+
+// SubmitOptions — дополнительные параметры выражения.
+type SubmitOptions struct {
+	// CallbackURL — адрес, на который оркестратор отправит результат.
+	CallbackURL string
+}
+
+// ListFilter — параметры выборки списка выражений. Пустые поля не ограничивают выборку.
+type ListFilter struct {
+	Status        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Sort — поле сортировки: id или created_at, с префиксом "-" — по убыванию.
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// Page — страница списка выражений. Если NextCursor не пуст, его можно
+// передать в ListFilter.Cursor, чтобы получить следующую страницу.
+type Page struct {
+	Expressions []*Expression `json:"expressions"`
+	NextCursor  string        `json:"next_cursor,omitempty"`
+}
+
+// BatchResult — итог отправки одного выражения из пачки.
+type BatchResult struct {
+	ID  int
+	Err error
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Wait ждёт, пока выражение получит финальный статус, и возвращает его.
+// Клиент подписывается на поток событий выражения; если поток недоступен
+// или оборвался, он опрашивает выражение с периодом WithPollInterval.
+func (c *Client) Wait(ctx context.Context, id int) (*Expression, error) {
+	if err := c.waitEvents(ctx, id); noAccess(err) {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.poll(ctx, id)
+}
+
+// waitEvents читает поток событий выражения до события result.
+func (c *Client) waitEvents(ctx context.Context, id int) error {
+	resp, err := c.send(ctx, http.MethodGet, fmt.Sprintf("/api/v1/expressions/%d/events", id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "event: result" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("поток событий закрыт до получения результата")
+}
+
+// poll запрашивает выражение, пока оно не получит финальный статус.
+func (c *Client) poll(ctx context.Context, id int) (*Expression, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		expr, err := c.Get(ctx, id)
+		if err == nil && IsFinal(expr.Status) {
+			return expr, nil
+		}
+		if noAccess(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// noAccess сообщает, что выражения нет или к нему нет доступа: повторный запрос ничего не изменит.
+func noAccess(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrForbidden)
+}
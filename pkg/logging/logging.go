@@ -0,0 +1,59 @@
+// Package logging настраивает log/slog для оркестратора и агента и задаёт
+// общие имена полей, чтобы записи обоих компонентов можно было искать одинаково.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// New создаёт логгер по переменным среды LOG_LEVEL (debug, info, warn, error;
+// по умолчанию info) и LOG_FORMAT (text или json; по умолчанию text).
+func New(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(os.Getenv("LOG_LEVEL"))}
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ParseLevel разбирает уровень логирования. Неизвестные значения считаются info.
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Discard — логгер, который ничего не пишет. Удобен в тестах.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func ExpressionID(id int) slog.Attr {
+	return slog.Int("expression_id", id)
+}
+
+func TaskID(id int) slog.Attr {
+	return slog.Int("task_id", id)
+}
+
+func AgentID(id string) slog.Attr {
+	return slog.String("agent_id", id)
+}
+
+func Operation(op string) slog.Attr {
+	return slog.String("operation", op)
+}
+
+func Duration(d time.Duration) slog.Attr {
+	return slog.Duration("duration", d)
+}
+
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
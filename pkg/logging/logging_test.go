@@ -0,0 +1,52 @@
+package logging_test
+
+import (
+	"Calc_2GO/pkg/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		format    string
+		wantDebug bool
+		wantJSON  bool
+	}{
+		{"По умолчанию", "", "", false, false},
+		{"Уровень debug", "debug", "text", true, false},
+		{"Формат JSON", "WARN", "json", false, true},
+		{"Неизвестный уровень", "verbose", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tt.level)
+			t.Setenv("LOG_FORMAT", tt.format)
+
+			var buf bytes.Buffer
+			logger := logging.New(&buf)
+
+			if got := logger.Enabled(context.Background(), slog.LevelDebug); got != tt.wantDebug {
+				t.Fatalf("❌ %s: debug включён: %v, ожидали %v", tt.name, got, tt.wantDebug)
+			}
+
+			logger.Error("тест", logging.TaskID(7), logging.Duration(time.Second))
+			var record map[string]any
+			isJSON := json.Unmarshal(buf.Bytes(), &record) == nil
+			if isJSON != tt.wantJSON {
+				t.Fatalf("❌ %s: ожидали JSON: %v, получили %q", tt.name, tt.wantJSON, buf.String())
+			}
+			if isJSON && record["task_id"] != float64(7) {
+				t.Fatalf("❌ %s: нет поля task_id: %v", tt.name, record)
+			}
+			fmt.Printf("✅ %s: %s", tt.name, buf.String())
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package tracing_test
+
+import (
+	"Calc_2GO/pkg/tracing"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSetup(t *testing.T) {
+	received := make(chan *collectortrace.ExportTraceServiceRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("❌ неожиданный путь экспорта: %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			t.Errorf("❌ не удалось разобрать OTLP-запрос: %v", err)
+		}
+		received <- &req
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	shutdown, err := tracing.Setup(context.Background(), "calc-test")
+	if err != nil {
+		t.Fatalf("❌ Ошибка настройки трассировки: %v", err)
+	}
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "expression")
+	carrier := tracing.Inject(ctx)
+	span.End()
+
+	if got := tracing.Extract(context.Background(), carrier); !trace.SpanContextFromContext(got).IsValid() {
+		t.Fatalf("❌ Контекст трассировки не восстановлен из %v", carrier)
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("❌ Ошибка при остановке экспорта: %v", err)
+	}
+
+	req := <-received
+	rs := req.ResourceSpans[0]
+	var service string
+	for _, attr := range rs.Resource.Attributes {
+		if attr.Key == "service.name" {
+			service = attr.Value.GetStringValue()
+		}
+	}
+	name := rs.ScopeSpans[0].Spans[0].Name
+	if service != "calc-test" || name != "expression" {
+		t.Fatalf("❌ Ожидали спан expression сервиса calc-test, а получили %s сервиса %s", name, service)
+	}
+	fmt.Printf("✅ Спан %s экспортирован по OTLP, traceparent %s\n", name, carrier["traceparent"])
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := tracing.Setup(context.Background(), "calc-test")
+	if err != nil || shutdown(context.Background()) != nil {
+		t.Fatalf("❌ Без адреса коллектора трассировка должна выключаться без ошибок: %v", err)
+	}
+	fmt.Println("✅ Без OTEL_EXPORTER_OTLP_ENDPOINT экспорт выключен")
+}